@@ -53,7 +53,7 @@ func NewRootCmd() *cobra.Command {
 			wg := &sync.WaitGroup{}
 
 			k8s := k8s.NewK8S(config.GetK8SOptions())
-			ing := ingress.NewIngress(config.GetIngressOptions())
+			ing := ingress.NewIngress(config.GetIngressOptions(), k8s)
 			api := api.NewAPI(config.GetAPIOptions(), k8s, ing)
 
 			go k8s.Start(ctx, wg)
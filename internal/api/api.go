@@ -18,11 +18,14 @@ type API struct {
 	ing *ingress.Ingress
 
 	httpServer *http.Server
+	// adminServer serves the endpoints exposing and closing the connections of players.
+	adminServer *http.Server
 }
 
 // NewAPI creates a new api instance with the given host and port
 func NewAPI(apiOptions config.APIOptions, k8s *k8s.K8S, ing *ingress.Ingress) *API {
 	r := http.NewServeMux()
+	admin := http.NewServeMux()
 	api := &API{
 		k8s: k8s,
 		ing: ing,
@@ -31,10 +34,18 @@ func NewAPI(apiOptions config.APIOptions, k8s *k8s.K8S, ing *ingress.Ingress) *A
 			Addr:    apiOptions.GetAddress(),
 			Handler: r,
 		},
+		adminServer: &http.Server{
+			Addr:    apiOptions.GetAdminAddress(),
+			Handler: admin,
+		},
 	}
 	r.Handle("/metrics", promhttp.Handler())
 	r.HandleFunc("/health/live", api.healthLive)
 	r.HandleFunc("/health/ready", api.healthReady)
+	r.HandleFunc("GET /routes", api.routes)
+	r.HandleFunc("GET /routes/{hostname}", api.route)
+	admin.HandleFunc("GET /connections", api.connections)
+	admin.HandleFunc("DELETE /connections/{id}", api.connection)
 
 	return api
 }
@@ -47,21 +58,23 @@ func (api *API) Start(context context.Context, wg *sync.WaitGroup) {
 	}).Debug("Starting API")
 
 	wg.Add(1)
-	go func() {
-		if err := api.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logrus.WithFields(logrus.Fields{
-				"addr": api.httpServer.Addr,
-			}).Fatal("Failed to start API")
-		}
-	}()
+	for _, server := range []*http.Server{api.httpServer, api.adminServer} {
+		go func(server *http.Server) {
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logrus.WithFields(logrus.Fields{
+					"addr": server.Addr,
+				}).Fatal("Failed to start API")
+			}
+		}(server)
+	}
 
 	logrus.WithFields(logrus.Fields{
-		"addr": api.httpServer.Addr,
+		"addr":      api.httpServer.Addr,
+		"adminAddr": api.adminServer.Addr,
 	}).Info("Started API")
-	for {
-		<-context.Done()
-		return
-	}
+	<-context.Done()
+	// keep serving the health checks while the ingress is draining
+	<-api.ing.Drained()
 }
 
 // Stop the api
@@ -70,10 +83,12 @@ func (a *API) Stop(wg *sync.WaitGroup) {
 		"addr": a.httpServer.Addr,
 	}).Debug("Stopping API")
 
-	if err := a.httpServer.Close(); err != nil {
-		logrus.WithFields(logrus.Fields{
-			"addr": a.httpServer.Addr,
-		}).Error("Failed to stop API")
+	for _, server := range []*http.Server{a.httpServer, a.adminServer} {
+		if err := server.Close(); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"addr": server.Addr,
+			}).Error("Failed to stop API")
+		}
 	}
 
 	wg.Done()
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+func (api *API) connections(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, http.StatusOK, api.ing.Connections())
+}
+
+func (api *API) connection(writer http.ResponseWriter, request *http.Request) {
+	id := request.PathValue("id")
+	if !api.ing.Kick(id) {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	logrus.WithField("id", id).Info("Kicked connection")
+	writer.WriteHeader(http.StatusNoContent)
+}
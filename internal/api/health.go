@@ -1,22 +1,87 @@
 package api
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+
+	"github.com/qumine/ingress-controller/internal/routing"
+)
+
+// healthResponse represents the health of the components.
+type healthResponse struct {
+	Status    string           `json:"status"`
+	K8S       k8sHealth        `json:"k8s"`
+	Ingress   ingressHealth    `json:"ingress"`
+	Listeners []listenerHealth `json:"listeners"`
+	Routes    routesHealth     `json:"routes"`
+}
+
+type k8sHealth struct {
+	Status    string     `json:"status"`
+	Synced    bool       `json:"synced"`
+	LastEvent *time.Time `json:"lastEvent,omitempty"`
+}
+
+type ingressHealth struct {
+	Status      string `json:"status"`
+	Connections int64  `json:"connections"`
+}
+
+type listenerHealth struct {
+	Name   string `json:"name,omitempty"`
+	Addr   string `json:"addr"`
+	Status string `json:"status"`
+}
+
+type routesHealth struct {
+	Count int `json:"count"`
+}
 
 func (api *API) healthLive(writer http.ResponseWriter, request *http.Request) {
-	writer.WriteHeader(http.StatusOK)
-	writer.Write([]byte{})
+	health := api.health()
+	health.Status = "up"
+	writeJSON(writer, http.StatusOK, health)
 }
 
 func (api *API) healthReady(writer http.ResponseWriter, request *http.Request) {
-	details := make(map[string]string)
-	details["k8s"] = api.k8s.Status
-	details["server"] = api.ing.Status
-
-	if api.k8s.Status == "up" && api.ing.Status == "up" {
-		writer.WriteHeader(http.StatusOK)
-		writer.Write([]byte{})
+	health := api.health()
+	if health.Status == "up" {
+		writeJSON(writer, http.StatusOK, health)
 	} else {
-		writer.WriteHeader(http.StatusServiceUnavailable)
-		writer.Write([]byte{})
+		writeJSON(writer, http.StatusServiceUnavailable, health)
+	}
+}
+
+// health collects the health of all components, the status is only up if all components are up.
+func (api *API) health() healthResponse {
+	health := healthResponse{
+		Status: "up",
+		K8S: k8sHealth{
+			Status: api.k8s.Status,
+			Synced: api.k8s.Synced(),
+		},
+		Ingress: ingressHealth{
+			Status:      api.ing.Status,
+			Connections: api.ing.ActiveConnections(),
+		},
+		Listeners: make([]listenerHealth, 0),
+		Routes: routesHealth{
+			Count: len(routing.Routes()),
+		},
+	}
+	if lastEvent := api.k8s.LastEvent(); !lastEvent.IsZero() {
+		health.K8S.LastEvent = &lastEvent
+	}
+	if health.K8S.Status != "up" || health.Ingress.Status != "up" {
+		health.Status = "down"
+	}
+
+	for _, l := range api.ing.Listeners() {
+		status := l.Status()
+		health.Listeners = append(health.Listeners, listenerHealth{Name: l.Name, Addr: l.Addr, Status: status})
+		if status != "up" {
+			health.Status = "down"
+		}
 	}
+	return health
 }
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/qumine/ingress-controller/internal/forwarding"
+	"github.com/qumine/ingress-controller/internal/proxyproto"
+	"github.com/qumine/ingress-controller/internal/routing"
+	"github.com/sirupsen/logrus"
+)
+
+// routeResponse represents a route of the routing table.
+type routeResponse struct {
+	Hostname        string         `json:"hostname"`
+	Backends        []string       `json:"backends"`
+	Source          sourceResponse `json:"source"`
+	Default         bool           `json:"default"`
+	Strategy        string         `json:"strategy"`
+	Pool            string         `json:"pool,omitempty"`
+	Priority        int            `json:"priority"`
+	Listeners       []string       `json:"listeners,omitempty"`
+	Workload        string         `json:"workload,omitempty"`
+	IdleTimeout     string         `json:"idleTimeout,omitempty"`
+	ProxyProtocol   string         `json:"proxyProtocol,omitempty"`
+	StatusCacheTTL  string         `json:"statusCacheTTL,omitempty"`
+	StatusAggregate bool           `json:"statusAggregate,omitempty"`
+	StatusMOTD      string         `json:"statusMOTD,omitempty"`
+	Rewrite         string         `json:"rewrite,omitempty"`
+	Forwarding      string         `json:"forwarding,omitempty"`
+	OfflineMOTD     string         `json:"offlineMOTD,omitempty"`
+	OfflineMessage  string         `json:"offlineMessage,omitempty"`
+	Created         time.Time      `json:"created"`
+	Connections     int            `json:"connections"`
+}
+
+type sourceResponse struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	UID       string `json:"uid"`
+}
+
+// resolutionResponse represents the resolution of a hostname.
+type resolutionResponse struct {
+	Hostname string           `json:"hostname"`
+	Listener string           `json:"listener,omitempty"`
+	Match    routing.Match    `json:"match"`
+	Lookups  []lookupResponse `json:"lookups"`
+	Route    *routeResponse   `json:"route,omitempty"`
+}
+
+type lookupResponse struct {
+	Match routing.Match `json:"match"`
+	Key   string        `json:"key,omitempty"`
+	Found bool          `json:"found"`
+}
+
+func (api *API) routes(writer http.ResponseWriter, request *http.Request) {
+	connections := api.connectionsByBackend()
+	routes := routing.Routes()
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Frontend == routes[j].Frontend {
+			return routes[i].Source.UID < routes[j].Source.UID
+		}
+		return routes[i].Frontend < routes[j].Frontend
+	})
+
+	response := make([]routeResponse, 0, len(routes))
+	for _, route := range routes {
+		response = append(response, newRouteResponse(route, connections))
+	}
+	writeJSON(writer, http.StatusOK, response)
+}
+
+func (api *API) route(writer http.ResponseWriter, request *http.Request) {
+	resolution := routing.Resolve(request.URL.Query().Get("listener"), request.PathValue("hostname"))
+
+	response := resolutionResponse{
+		Hostname: resolution.Frontend,
+		Listener: resolution.Listener,
+		Match:    resolution.Match,
+		Lookups:  make([]lookupResponse, 0, len(resolution.Lookups)),
+	}
+	for _, lookup := range resolution.Lookups {
+		response.Lookups = append(response.Lookups, lookupResponse{Match: lookup.Match, Key: lookup.Key, Found: lookup.Found})
+	}
+	if resolution.Match == routing.MatchNone {
+		writeJSON(writer, http.StatusNotFound, response)
+		return
+	}
+
+	route := newRouteResponse(resolution.Route, api.connectionsByBackend())
+	response.Route = &route
+	writeJSON(writer, http.StatusOK, response)
+}
+
+// connectionsByBackend counts the active connections of every backend.
+func (api *API) connectionsByBackend() map[string]int {
+	connections := make(map[string]int)
+	for _, connection := range api.ing.Connections() {
+		connections[connection.Backend]++
+	}
+	return connections
+}
+
+func newRouteResponse(route routing.Route, connections map[string]int) routeResponse {
+	response := routeResponse{
+		Hostname: route.Frontend,
+		Backends: route.Backends,
+		Source: sourceResponse{
+			Namespace: route.Source.Namespace,
+			Name:      route.Source.Name,
+			UID:       route.Source.UID,
+		},
+		Default:         route.Default,
+		Strategy:        string(route.Strategy),
+		Pool:            route.Pool,
+		Priority:        route.Priority,
+		Listeners:       route.Listeners,
+		OfflineMOTD:     route.OfflineMOTD,
+		OfflineMessage:  route.OfflineMessage,
+		StatusAggregate: route.StatusAggregate,
+		StatusMOTD:      route.StatusMOTD,
+		Created:         route.Created,
+	}
+	if route.Workload != nil {
+		response.Workload = route.Workload.String()
+	}
+	if route.IdleTimeout > 0 {
+		response.IdleTimeout = route.IdleTimeout.String()
+	}
+	if route.ProxyProtocol != proxyproto.None {
+		response.ProxyProtocol = route.ProxyProtocol.String()
+	}
+	if route.StatusCacheTTL > 0 {
+		response.StatusCacheTTL = route.StatusCacheTTL.String()
+	}
+	if route.Rewrite != nil {
+		response.Rewrite = route.Rewrite.String()
+	}
+	if route.Forwarding != forwarding.None {
+		response.Forwarding = route.Forwarding.String()
+	}
+	if response.Backends == nil {
+		response.Backends = []string{}
+	}
+	for _, backend := range route.Backends {
+		response.Connections += connections[backend]
+	}
+	return response
+}
+
+func writeJSON(writer http.ResponseWriter, status int, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	if err := json.NewEncoder(writer).Encode(value); err != nil {
+		logrus.WithError(err).Error("Failed to write response")
+	}
+}
@@ -0,0 +1,12 @@
+package forwarding
+
+import (
+	"strings"
+)
+
+// BungeeCordAddress returns the handshake address with the address and UUID of the player appended
+// like BungeeCord does. Markers of modded clients are removed from the address.
+func BungeeCordAddress(address string, ip string, uuid string) string {
+	hostname := strings.SplitN(address, "\x00", 2)[0]
+	return hostname + "\x00" + ip + "\x00" + strings.ReplaceAll(uuid, "-", "")
+}
@@ -0,0 +1,39 @@
+package forwarding
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineUUID(t *testing.T) {
+	assert.Equal(t, "b50ad385-829d-3141-a216-7e7d7539ba7f", OfflineUUID("Notch"))
+}
+
+func TestBungeeCordAddress(t *testing.T) {
+	result := BungeeCordAddress("mc.example.com\x00FML2\x00", "203.0.113.7", "069a79f4-44e9-4a2c-9af1-2a3e5eb14c7b")
+	assert.Equal(t, "mc.example.com\x00203.0.113.7\x00069a79f444e94a2c9af12a3e5eb14c7b", result)
+}
+
+func TestVelocityPlayerInfo(t *testing.T) {
+	secret := []byte("secret")
+	result, err := VelocityPlayerInfo(secret, "203.0.113.7", "069a79f4-44e9-4a2c-9af1-2a3e5eb14c7b", "Notch")
+	require.NoError(t, err)
+
+	payload := append([]byte{0x01, 0x0B}, "203.0.113.7"...)
+	payload = append(payload, 0x06, 0x9a, 0x79, 0xf4, 0x44, 0xe9, 0x4a, 0x2c, 0x9a, 0xf1, 0x2a, 0x3e, 0x5e, 0xb1, 0x4c, 0x7b)
+	payload = append(append(payload, 0x05), "Notch"...)
+	payload = append(payload, 0x00)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	assert.Equal(t, append(mac.Sum(nil), payload...), result)
+}
+
+func TestVelocityPlayerInfoInvalidUUID(t *testing.T) {
+	_, err := VelocityPlayerInfo([]byte("secret"), "203.0.113.7", "invalid", "Notch")
+	assert.Error(t, err)
+}
@@ -0,0 +1,66 @@
+package forwarding
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Mode is the mode used to forward the identity of players to the backend.
+type Mode string
+
+const (
+	// None disables the forwarding.
+	None Mode = ""
+	// BungeeCord appends the address and UUID of the player to the address of the handshake.
+	BungeeCord Mode = "bungeecord"
+	// Velocity answers the player info request of the backend with a payload signed by a shared secret.
+	Velocity Mode = "velocity"
+)
+
+// ParseMode parses a forwarding mode like bungeecord or velocity.
+func ParseMode(mode string) (Mode, error) {
+	switch Mode(strings.ToLower(mode)) {
+	case None, "none":
+		return None, nil
+	case BungeeCord:
+		return BungeeCord, nil
+	case Velocity:
+		return Velocity, nil
+	}
+	return None, fmt.Errorf("unsupported forwarding mode %q", mode)
+}
+
+func (m Mode) String() string {
+	if m == None {
+		return "none"
+	}
+	return string(m)
+}
+
+// OfflineUUID returns the UUID a server in offline mode assigns to the player.
+func OfflineUUID(name string) string {
+	hash := md5.Sum([]byte("OfflinePlayer:" + name))
+	hash[6] = hash[6]&0x0f | 0x30
+	hash[8] = hash[8]&0x3f | 0x80
+	return formatUUID(hash)
+}
+
+func formatUUID(uuid [16]byte) string {
+	value := hex.EncodeToString(uuid[:])
+	return value[0:8] + "-" + value[8:12] + "-" + value[12:16] + "-" + value[16:20] + "-" + value[20:32]
+}
+
+func parseUUID(uuid string) ([16]byte, error) {
+	var result [16]byte
+	value, err := hex.DecodeString(strings.ReplaceAll(uuid, "-", ""))
+	if err != nil {
+		return result, err
+	}
+	if len(value) != len(result) {
+		return result, fmt.Errorf("invalid UUID %q", uuid)
+	}
+	copy(result[:], value)
+	return result, nil
+}
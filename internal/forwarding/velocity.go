@@ -0,0 +1,45 @@
+package forwarding
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+
+	"github.com/qumine/ingress-controller/internal/proto"
+)
+
+const (
+	// VelocityChannel is the channel of the login plugin request for the player info.
+	VelocityChannel = "velocity:player_info"
+
+	// velocityVersion is the modern forwarding version without the signed chat key.
+	velocityVersion = 1
+)
+
+// VelocityPlayerInfo returns the player info payload signed with the secret as expected by the backend.
+func VelocityPlayerInfo(secret []byte, ip string, uuid string, name string) ([]byte, error) {
+	id, err := parseUUID(uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := new(bytes.Buffer)
+	if err := proto.WriteVarInt(payload, velocityVersion); err != nil {
+		return nil, err
+	}
+	if err := proto.WriteString(payload, ip); err != nil {
+		return nil, err
+	}
+	payload.Write(id[:])
+	if err := proto.WriteString(payload, name); err != nil {
+		return nil, err
+	}
+	// no properties
+	if err := proto.WriteVarInt(payload, 0); err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload.Bytes())
+	return append(mac.Sum(nil), payload.Bytes()...), nil
+}
@@ -0,0 +1,140 @@
+package ingress
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/qumine/ingress-controller/internal/metrics"
+)
+
+// Connection represents a client connection relayed to a backend.
+type Connection struct {
+	ID              string    `json:"id"`
+	Client          string    `json:"client"`
+	Listener        string    `json:"listener,omitempty"`
+	Hostname        string    `json:"hostname"`
+	Backend         string    `json:"backend"`
+	ProtocolVersion int       `json:"protocolVersion"`
+	Player          string    `json:"player,omitempty"`
+	PlayerUUID      string    `json:"playerUUID,omitempty"`
+	Started         time.Time `json:"started"`
+	BytesUpstream   int64     `json:"bytesUpstream"`
+	BytesDownstream int64     `json:"bytesDownstream"`
+}
+
+// relayedConnection is a live entry of the connection registry.
+type relayedConnection struct {
+	Connection
+	bytesUpstream   int64
+	bytesDownstream int64
+	cancel          context.CancelFunc
+}
+
+// connectionRegistry tracks the relayed connections of the ingress.
+type connectionRegistry struct {
+	mutex       sync.Mutex
+	lastID      uint64
+	connections map[string]*relayedConnection
+}
+
+func newConnectionRegistry() *connectionRegistry {
+	return &connectionRegistry{
+		connections: make(map[string]*relayedConnection),
+	}
+}
+
+// register adds a connection to the registry, the returned context is cancelled once the connection is kicked.
+func (r *connectionRegistry) register(parent context.Context, connection Connection) (context.Context, *relayedConnection) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.lastID++
+	connection.ID = strconv.FormatUint(r.lastID, 10)
+	connection.Started = time.Now()
+
+	ctx, cancel := context.WithCancel(parent)
+	relayed := &relayedConnection{Connection: connection, cancel: cancel}
+	r.connections[connection.ID] = relayed
+	return ctx, relayed
+}
+
+// unregister removes a connection from the registry.
+func (r *connectionRegistry) unregister(relayed *relayedConnection) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	relayed.cancel()
+	delete(r.connections, relayed.ID)
+}
+
+// list returns a snapshot of all connections ordered by their start.
+func (r *connectionRegistry) list() []Connection {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	connections := make([]Connection, 0, len(r.connections))
+	for _, relayed := range r.connections {
+		connection := relayed.Connection
+		connection.BytesUpstream = atomic.LoadInt64(&relayed.bytesUpstream)
+		connection.BytesDownstream = atomic.LoadInt64(&relayed.bytesDownstream)
+		connections = append(connections, connection)
+	}
+	sort.Slice(connections, func(i, j int) bool {
+		if connections[i].Started.Equal(connections[j].Started) {
+			return connections[i].ID < connections[j].ID
+		}
+		return connections[i].Started.Before(connections[j].Started)
+	})
+	return connections
+}
+
+// kick terminates the relay of the connection.
+func (r *connectionRegistry) kick(id string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	relayed, ok := r.connections[id]
+	if !ok {
+		return false
+	}
+	relayed.cancel()
+	return true
+}
+
+// Connections returns all connections relayed by the ingress.
+func (ing *Ingress) Connections() []Connection {
+	return ing.connections.list()
+}
+
+// Kick terminates the relayed connection with the id, returns false if there is no such connection.
+func (ing *Ingress) Kick(id string) bool {
+	return ing.connections.kick(id)
+}
+
+// countingWriter counts the bytes written for the registry and metrics.
+type countingWriter struct {
+	writer  io.Writer
+	count   *int64
+	counter prometheus.Counter
+}
+
+func newCountingWriter(writer io.Writer, count *int64, direction string, route string) *countingWriter {
+	return &countingWriter{
+		writer:  writer,
+		count:   count,
+		counter: metrics.BytesTotal.With(prometheus.Labels{"direction": direction, "route": route}),
+	}
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	atomic.AddInt64(w.count, int64(n))
+	w.counter.Add(float64(n))
+	return n, err
+}
@@ -0,0 +1,35 @@
+package ingress
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionRegistry(t *testing.T) {
+	registry := newConnectionRegistry()
+
+	ctx, relayed := registry.register(context.Background(), Connection{
+		Client:   "203.0.113.7:51234",
+		Hostname: "mc.example.com",
+		Backend:  "10.0.0.1:25565",
+	})
+	newCountingWriter(io.Discard, &relayed.bytesUpstream, "upstream", relayed.Backend).Write([]byte("hello"))
+
+	connections := registry.list()
+	require.Len(t, connections, 1)
+	assert.Equal(t, relayed.ID, connections[0].ID)
+	assert.Equal(t, "mc.example.com", connections[0].Hostname)
+	assert.Equal(t, int64(5), connections[0].BytesUpstream)
+	assert.Equal(t, int64(0), connections[0].BytesDownstream)
+
+	assert.False(t, registry.kick("unknown"))
+	assert.True(t, registry.kick(relayed.ID))
+	assert.Error(t, ctx.Err())
+
+	registry.unregister(relayed)
+	assert.Empty(t, registry.list())
+}
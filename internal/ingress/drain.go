@@ -0,0 +1,57 @@
+package ingress
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	drainInterval = time.Second
+)
+
+// ActiveConnections returns the amount of active client connections.
+func (ing *Ingress) ActiveConnections() int64 {
+	return atomic.LoadInt64(&ing.active)
+}
+
+// Drained returns a channel which is closed once the ingress is stopped and all connections are closed.
+func (ing *Ingress) Drained() <-chan struct{} {
+	return ing.drained
+}
+
+// drain waits until all active connections are closed or the drain timeout is exceeded.
+func (ing *Ingress) drain() {
+	if ing.drainTimeout <= 0 {
+		return
+	}
+
+	timeout := time.NewTimer(ing.drainTimeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+
+	reported := int64(-1)
+	for {
+		remaining := ing.ActiveConnections()
+		if remaining == 0 {
+			logrus.Info("Drained connections")
+			return
+		}
+		if remaining != reported {
+			logrus.WithFields(logrus.Fields{
+				"remaining": remaining,
+				"timeout":   ing.drainTimeout,
+			}).Info("Draining connections")
+			reported = remaining
+		}
+
+		select {
+		case <-timeout.C:
+			logrus.WithField("remaining", ing.ActiveConnections()).Warn("Drain timeout exceeded, closing remaining connections")
+			return
+		case <-ticker.C:
+		}
+	}
+}
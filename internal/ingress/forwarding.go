@@ -0,0 +1,135 @@
+package ingress
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/qumine/ingress-controller/internal/forwarding"
+	"github.com/qumine/ingress-controller/internal/metrics"
+	"github.com/qumine/ingress-controller/internal/proto"
+	"github.com/qumine/ingress-controller/internal/routing"
+	"github.com/sirupsen/logrus"
+)
+
+// bufferedConn is a connection whose reads are served by a reader buffering the connection.
+type bufferedConn struct {
+	net.Conn
+	reader io.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// forwardingAllowed reports whether the identity of the player is forwarded. As the ingress does not authenticate
+// players, forwarding has to be allowed explicitly, otherwise servers requiring forwarding reject the player.
+func (ing *Ingress) forwardingAllowed(client net.Conn, route routing.Route) bool {
+	if ing.allowUnauthenticatedForwarding {
+		return true
+	}
+	logrus.WithFields(logrus.Fields{
+		"client":     client.RemoteAddr(),
+		"forwarding": route.Forwarding,
+	}).Warn("not forwarding player, unauthenticated forwarding is not allowed")
+	metrics.ErrorsTotal.With(prometheus.Labels{"error": "ForwardingNotAllowed"}).Inc()
+	return false
+}
+
+// forwardingIdentity returns the address and UUID of the player forwarded to the backend. The UUID send by the client
+// is used if present, otherwise the UUID the backend would assign in offline mode.
+func forwardingIdentity(client net.Conn, req *request) (string, string, error) {
+	if req.player == nil {
+		return "", "", errors.New("player is unknown")
+	}
+
+	ip, _, err := net.SplitHostPort(client.RemoteAddr().String())
+	if err != nil {
+		return "", "", err
+	}
+
+	uuid := req.player.UUID
+	if uuid == "" {
+		uuid = forwarding.OfflineUUID(req.player.Name)
+	}
+	return ip, uuid, nil
+}
+
+// forwardBungeeCord appends the address and UUID of the player to the address of the handshake.
+func (ing *Ingress) forwardBungeeCord(client net.Conn, req *request, handshake *proto.Handshake) error {
+	ip, uuid, err := forwardingIdentity(client, req)
+	if err != nil {
+		return err
+	}
+	handshake.ServerAddress = forwarding.BungeeCordAddress(handshake.ServerAddress, ip, uuid)
+
+	logrus.WithFields(logrus.Fields{
+		"client": client.RemoteAddr(),
+		"player": req.playerName(),
+		"uuid":   uuid,
+	}).Debug("forwarding player with bungeecord")
+	return nil
+}
+
+// forwardVelocity answers the player info request of the upstream with the signed identity of the player. Other packets
+// of the upstream are passed on to the client, the returned connection has to be used for further reads of the upstream.
+func (ing *Ingress) forwardVelocity(context context.Context, client net.Conn, upstream net.Conn, req *request, route routing.Route) (net.Conn, error) {
+	if route.ForwardingSecret == nil {
+		return nil, errors.New("route has no forwarding secret")
+	}
+	ip, uuid, err := forwardingIdentity(client, req)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := ing.k8s.GetSecret(context, route.ForwardingSecret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting forwarding secret %s failed", route.ForwardingSecret)
+	}
+
+	if err := upstream.SetReadDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(upstream)
+	packet, err := proto.ReadPacket(reader, upstream.RemoteAddr(), proto.StateLogin)
+	if err != nil {
+		return nil, err
+	}
+	if err := upstream.SetReadDeadline(noDeadline); err != nil {
+		return nil, err
+	}
+	conn := &bufferedConn{Conn: upstream, reader: reader}
+
+	var pluginRequest *proto.LoginPluginRequest
+	if packet.PacketID == proto.LoginPluginRequestID {
+		if pluginRequest, err = proto.ReadLoginPluginRequest(packet.Data); err != nil {
+			return nil, err
+		}
+	}
+	if pluginRequest == nil || pluginRequest.Channel != forwarding.VelocityChannel {
+		logrus.WithFields(logrus.Fields{
+			"client":   client.RemoteAddr(),
+			"upstream": upstream.RemoteAddr(),
+			"packetID": packet.PacketID,
+		}).Warn("upstream did not request velocity forwarding")
+		return conn, proto.WritePacket(client, client.RemoteAddr(), packet)
+	}
+
+	data, err := forwarding.VelocityPlayerInfo(secret, ip, uuid, req.player.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := proto.WriteLoginPluginResponse(upstream, upstream.RemoteAddr(), pluginRequest.MessageID, data); err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"client": client.RemoteAddr(),
+		"player": req.playerName(),
+		"uuid":   uuid,
+	}).Debug("forwarding player with velocity")
+	return conn, nil
+}
@@ -0,0 +1,91 @@
+package ingress
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/qumine/ingress-controller/internal/forwarding"
+	"github.com/qumine/ingress-controller/internal/proto"
+	"github.com/qumine/ingress-controller/internal/routing"
+	"github.com/sirupsen/logrus"
+)
+
+// upstreamHandshake returns the handshake send to the upstream or nil if the handshake of the client is relayed as is.
+func (ing *Ingress) upstreamHandshake(client net.Conn, req *request, route routing.Route, backend string) (*proto.Handshake, error) {
+	if req.handshake == nil {
+		return nil, nil
+	}
+
+	handshake := *req.handshake
+	changed := false
+	if route.Rewrite != nil {
+		if err := rewriteHandshake(&handshake, route.Rewrite, backend); err != nil {
+			return nil, err
+		}
+		changed = true
+	}
+	if req.nextState == proto.StateLogin && route.Forwarding == forwarding.BungeeCord && ing.forwardingAllowed(client, route) {
+		if err := ing.forwardBungeeCord(client, req, &handshake); err != nil {
+			return nil, err
+		}
+		changed = true
+	}
+	if !changed {
+		return nil, nil
+	}
+	return &handshake, nil
+}
+
+// rewriteHandshake sets the address and port of the handshake to the rewrite or to the backend, keeping anything appended to the address.
+func rewriteHandshake(handshake *proto.Handshake, rewrite *routing.Rewrite, backend string) error {
+	host, port := rewrite.Host, rewrite.Port
+	if rewrite.Backend {
+		backendHost, backendPort, err := net.SplitHostPort(backend)
+		if err != nil {
+			return err
+		}
+		p, err := strconv.ParseUint(backendPort, 10, 16)
+		if err != nil {
+			return errors.Wrapf(err, "invalid port of backend %s", backend)
+		}
+		host, port = backendHost, uint16(p)
+	}
+
+	if host != "" {
+		// keep the markers appended to the address like the \x00FML\x00 of forge clients
+		suffix := ""
+		if i := strings.IndexByte(handshake.ServerAddress, 0); i >= 0 {
+			suffix = handshake.ServerAddress[i:]
+		}
+		handshake.ServerAddress = host + suffix
+	}
+	if port != 0 {
+		handshake.ServerPort = port
+	}
+	return nil
+}
+
+// replaceHandshake returns the pre read content with the handshake of the client replaced by the given handshake.
+func replaceHandshake(client net.Conn, req *request, preReadContent io.Reader, handshake *proto.Handshake) (io.Reader, error) {
+	content, err := io.ReadAll(preReadContent)
+	if err != nil {
+		return nil, err
+	}
+
+	replaced := new(bytes.Buffer)
+	if err := proto.WriteHandshake(replaced, client.RemoteAddr(), handshake); err != nil {
+		return nil, err
+	}
+	replaced.Write(content[req.handshakeSize:])
+
+	logrus.WithFields(logrus.Fields{
+		"client":  client.RemoteAddr(),
+		"address": handshake.ServerAddress,
+		"port":    handshake.ServerPort,
+	}).Debug("rewrote handshake")
+	return replaced, nil
+}
@@ -0,0 +1,65 @@
+package ingress
+
+import (
+	"testing"
+
+	"github.com/qumine/ingress-controller/internal/proto"
+	"github.com/qumine/ingress-controller/internal/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewriteHandshake(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Input    string
+		Rewrite  *routing.Rewrite
+		Expected proto.Handshake
+	}{
+		{
+			Name:     "Host",
+			Input:    "mc.example.com",
+			Rewrite:  &routing.Rewrite{Host: "lobby.internal"},
+			Expected: proto.Handshake{ServerAddress: "lobby.internal", ServerPort: 25565},
+		},
+		{
+			Name:     "Host and port",
+			Input:    "mc.example.com",
+			Rewrite:  &routing.Rewrite{Host: "lobby.internal", Port: 25566},
+			Expected: proto.Handshake{ServerAddress: "lobby.internal", ServerPort: 25566},
+		},
+		{
+			Name:     "Backend",
+			Input:    "mc.example.com",
+			Rewrite:  &routing.Rewrite{Backend: true},
+			Expected: proto.Handshake{ServerAddress: "10.0.0.1", ServerPort: 25577},
+		},
+		{
+			Name:     "Forge",
+			Input:    "mc.example.com\x00FML\x00",
+			Rewrite:  &routing.Rewrite{Host: "lobby.internal"},
+			Expected: proto.Handshake{ServerAddress: "lobby.internal\x00FML\x00", ServerPort: 25565},
+		},
+		{
+			Name:     "Forge backend",
+			Input:    "mc.example.com\x00FML2\x00",
+			Rewrite:  &routing.Rewrite{Backend: true},
+			Expected: proto.Handshake{ServerAddress: "10.0.0.1\x00FML2\x00", ServerPort: 25577},
+		},
+		{
+			Name:     "Forge port",
+			Input:    "mc.example.com\x00FML\x00",
+			Rewrite:  &routing.Rewrite{Port: 25566},
+			Expected: proto.Handshake{ServerAddress: "mc.example.com\x00FML\x00", ServerPort: 25566},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			handshake := proto.Handshake{ServerAddress: tt.Input, ServerPort: 25565}
+			require.NoError(t, rewriteHandshake(&handshake, tt.Rewrite, "10.0.0.1:25577"))
+
+			assert.Equal(t, tt.Expected, handshake)
+		})
+	}
+}
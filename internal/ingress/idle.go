@@ -0,0 +1,165 @@
+package ingress
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/qumine/ingress-controller/internal/metrics"
+	"github.com/qumine/ingress-controller/internal/routing"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	idleCheckInterval = time.Minute
+	// markActiveThrottle is the minimum time between marking a workload active on connect.
+	markActiveThrottle = 10 * time.Second
+	// workloadTimeout is the timeout for scaling a workload or marking it active, so a slow API server does not
+	// stall logins.
+	workloadTimeout = 5 * time.Second
+)
+
+// idleAction is the action taken for a workload by the idle check.
+type idleAction int
+
+const (
+	idleActionNone idleAction = iota
+	idleActionMarkActive
+	idleActionScaleDown
+)
+
+// idleTracker tracks the connections of workloads to scale them down once they are idle.
+type idleTracker struct {
+	mutex     sync.Mutex
+	workloads map[string]*idleWorkload
+}
+
+type idleWorkload struct {
+	connections int
+	lastActive  time.Time
+	lastMarked  time.Time
+}
+
+func newIdleTracker() *idleTracker {
+	return &idleTracker{
+		workloads: make(map[string]*idleWorkload),
+	}
+}
+
+// connect registers a connection for the workload and reports whether the workload has to be marked active,
+// which is the case for the first connection unless it was marked recently.
+func (t *idleTracker) connect(workload *routing.Workload) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	w := t.get(workload)
+	w.connections++
+	w.lastActive = time.Now()
+	return w.connections == 1 && time.Since(w.lastMarked) >= markActiveThrottle
+}
+
+// disconnect unregisters a connection for the workload.
+func (t *idleTracker) disconnect(workload *routing.Workload) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	w := t.get(workload)
+	w.connections--
+	w.lastActive = time.Now()
+}
+
+// touch resets the last time the workload was active.
+func (t *idleTracker) touch(workload *routing.Workload) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.get(workload).lastActive = time.Now()
+}
+
+// marked records that the workload was marked active.
+func (t *idleTracker) marked(workload *routing.Workload) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.get(workload).lastMarked = time.Now()
+}
+
+// status returns the amount of connections and the last time the workload was active.
+func (t *idleTracker) status(workload *routing.Workload) (int, time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	w := t.get(workload)
+	return w.connections, w.lastActive
+}
+
+func (t *idleTracker) get(workload *routing.Workload) *idleWorkload {
+	w, ok := t.workloads[workload.String()]
+	if !ok {
+		w = &idleWorkload{lastActive: time.Now()}
+		t.workloads[workload.String()] = w
+	}
+	return w
+}
+
+// scaleDownIdle periodically marks workloads with connections as active and scales down idle workloads.
+func (ing *Ingress) scaleDownIdle(context context.Context) {
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-context.Done():
+			return
+		case <-ticker.C:
+			checked := make(map[string]bool)
+			for _, route := range routing.Routes() {
+				if route.Workload == nil || route.IdleTimeout <= 0 || checked[route.Workload.String()] {
+					continue
+				}
+				checked[route.Workload.String()] = true
+				ing.checkIdle(context, route)
+			}
+		}
+	}
+}
+
+// nextIdleAction decides whether a workload with the given connections on this ingress is marked active
+// or scaled down.
+func nextIdleAction(connections int, lastActive time.Time, idleTimeout time.Duration, now time.Time) idleAction {
+	if connections > 0 {
+		return idleActionMarkActive
+	}
+	if now.Sub(lastActive) < idleTimeout {
+		return idleActionNone
+	}
+	return idleActionScaleDown
+}
+
+func (ing *Ingress) checkIdle(context context.Context, route routing.Route) {
+	connections, lastActive := ing.idle.status(route.Workload)
+	switch nextIdleAction(connections, lastActive, route.IdleTimeout, time.Now()) {
+	case idleActionMarkActive:
+		ing.markActive(context, route.Workload)
+	case idleActionScaleDown:
+		if _, err := ing.k8s.ScaleDown(context, route.Workload, route.IdleTimeout); err != nil {
+			logrus.WithError(err).WithField("workload", route.Workload).Error("scaling down workload failed")
+			metrics.ErrorsTotal.With(prometheus.Labels{"error": "ScaleDownFailed"}).Inc()
+			return
+		}
+		ing.idle.touch(route.Workload)
+	}
+}
+
+// markActive marks the workload active for all ingresses.
+func (ing *Ingress) markActive(ctx context.Context, workload *routing.Workload) {
+	ctx, cancel := context.WithTimeout(ctx, workloadTimeout)
+	defer cancel()
+	if err := ing.k8s.MarkActive(ctx, workload); err != nil {
+		logrus.WithError(err).WithField("workload", workload).Error("marking workload active failed")
+		metrics.ErrorsTotal.With(prometheus.Labels{"error": "MarkActiveFailed"}).Inc()
+		return
+	}
+	ing.idle.marked(workload)
+}
@@ -0,0 +1,68 @@
+package ingress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qumine/ingress-controller/internal/routing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdleTracker(t *testing.T) {
+	tracker := newIdleTracker()
+	workload := &routing.Workload{Namespace: "minecraft", Kind: "statefulset", Name: "survival"}
+
+	assert.True(t, tracker.connect(workload), "first connection marks active")
+	assert.False(t, tracker.connect(workload), "second connection does not mark active")
+	connections, _ := tracker.status(workload)
+	assert.Equal(t, 2, connections)
+
+	tracker.disconnect(workload)
+	tracker.disconnect(workload)
+	connections, lastActive := tracker.status(workload)
+	assert.Equal(t, 0, connections)
+	assert.WithinDuration(t, time.Now(), lastActive, time.Second)
+
+	tracker.marked(workload)
+	assert.False(t, tracker.connect(workload), "recently marked workload is not marked again")
+	tracker.disconnect(workload)
+
+	tracker.workloads[workload.String()].lastActive = time.Now().Add(-time.Hour)
+	tracker.touch(workload)
+	_, lastActive = tracker.status(workload)
+	assert.WithinDuration(t, time.Now(), lastActive, time.Second)
+}
+
+func TestNextIdleAction(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		Name        string
+		Connections int
+		LastActive  time.Time
+		Expected    idleAction
+	}{
+		{
+			Name:        "Connected",
+			Connections: 1,
+			LastActive:  now.Add(-time.Hour),
+			Expected:    idleActionMarkActive,
+		},
+		{
+			Name:       "Recently active",
+			LastActive: now.Add(-10 * time.Minute),
+			Expected:   idleActionNone,
+		},
+		{
+			Name:       "Idle",
+			LastActive: now.Add(-30 * time.Minute),
+			Expected:   idleActionScaleDown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			assert.Equal(t, tt.Expected, nextIdleAction(tt.Connections, tt.LastActive, 30*time.Minute, now))
+		})
+	}
+}
@@ -1,16 +1,21 @@
 package ingress
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"io"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/qumine/ingress-controller/internal/forwarding"
+	"github.com/qumine/ingress-controller/internal/k8s"
 	"github.com/qumine/ingress-controller/internal/metrics"
 	"github.com/qumine/ingress-controller/internal/proto"
+	"github.com/qumine/ingress-controller/internal/proxyproto"
 	"github.com/qumine/ingress-controller/internal/routing"
 	"github.com/qumine/ingress-controller/pkg/config"
 	"github.com/sirupsen/logrus"
@@ -18,6 +23,7 @@ import (
 
 const (
 	handshakeTimeout = 5 * time.Second
+	dialTimeout      = 3 * time.Second
 )
 
 var (
@@ -29,97 +35,179 @@ type Ingress struct {
 	// Status is the current status of the server.
 	Status string
 
-	addr  string
-	state proto.State
+	listeners []*Listener
+	state     proto.State
+
+	notFoundMOTD    string
+	notFoundVersion string
+	notFoundMessage string
+	offlineMOTD     string
+	offlineMessage  string
+
+	acceptProxyProtocol          bool
+	proxyProtocolTrustedNetworks []*net.IPNet
+
+	// allowUnauthenticatedForwarding enables forwarding the identity send by clients to the backends.
+	allowUnauthenticatedForwarding bool
+
+	k8s         *k8s.K8S
+	idle        *idleTracker
+	connections *connectionRegistry
+	statusCache *statusCache
+
+	// drainTimeout is the time to wait for active connections on shutdown.
+	drainTimeout time.Duration
+	// active is the amount of active client connections.
+	active int64
+	// relayContext is cancelled once the active connections should be closed.
+	relayContext context.Context
+	cancelRelays context.CancelFunc
+	drained      chan struct{}
+}
 
-	listener net.Listener
+// request represents the initial request of a client connection.
+type request struct {
+	// packet is the name of the initial packet.
+	packet string
+	// legacy is set if the initial packet was a legacy server list ping.
+	legacy bool
+
+	listener        *Listener
+	hostname        string
+	protocolVersion int
+	nextState       int
+	// handshake is the handshake of the client and handshakeSize its size in the pre read content.
+	handshake     *proto.Handshake
+	handshakeSize int
+	// player is the LoginStart of the client, only set on login.
+	player *proto.LoginStart
 }
 
 // NewIngress creates a new ingress instance with the options
-func NewIngress(ingressOptions config.IngressOptions) *Ingress {
+func NewIngress(ingressOptions config.IngressOptions, k8s *k8s.K8S) *Ingress {
+	proxyProtocolTrustedNetworks, err := ingressOptions.GetProxyProtocolTrustedNetworks()
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"cidrs": ingressOptions.ProxyProtocolTrustedCIDRs,
+		}).Fatal("Failed to parse PROXY protocol trusted CIDRs")
+	}
+	if ingressOptions.AcceptProxyProtocol && len(proxyProtocolTrustedNetworks) == 0 {
+		logrus.Fatal("Accepting PROXY protocol headers requires PROXY protocol trusted CIDRs")
+	}
+	if ingressOptions.AllowUnauthenticatedForwarding {
+		logrus.Warn("Forwarding unauthenticated players, any player can join services with a forwarding annotation as any other player")
+	}
+
+	listenerOptions, err := ingressOptions.GetListeners()
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"listeners": ingressOptions.Listeners,
+		}).Fatal("Failed to parse listeners")
+	}
+	listeners := make([]*Listener, 0, len(listenerOptions))
+	for _, options := range listenerOptions {
+		listeners = append(listeners, newListener(options))
+	}
+
+	relayContext, cancelRelays := context.WithCancel(context.Background())
 	return &Ingress{
-		addr: ingressOptions.GetAddress(),
+		listeners: listeners,
+
+		drainTimeout: ingressOptions.DrainTimeout,
+		relayContext: relayContext,
+		cancelRelays: cancelRelays,
+		drained:      make(chan struct{}),
+
+		notFoundMOTD:    ingressOptions.NotFoundMOTD,
+		notFoundVersion: ingressOptions.NotFoundVersion,
+		notFoundMessage: ingressOptions.NotFoundMessage,
+		offlineMOTD:     ingressOptions.OfflineMOTD,
+		offlineMessage:  ingressOptions.OfflineMessage,
+
+		acceptProxyProtocol:          ingressOptions.AcceptProxyProtocol,
+		proxyProtocolTrustedNetworks: proxyProtocolTrustedNetworks,
+
+		allowUnauthenticatedForwarding: ingressOptions.AllowUnauthenticatedForwarding,
+
+		k8s:         k8s,
+		idle:        newIdleTracker(),
+		connections: newConnectionRegistry(),
+		statusCache: newStatusCache(),
 	}
 }
 
 // Start the server
 func (ing *Ingress) Start(context context.Context, wg *sync.WaitGroup) {
 	defer ing.Stop(wg)
+	logrus.Debug("Starting ingress")
 
-	logrus.WithFields(logrus.Fields{
-		"addr": ing.addr,
-	}).Debug("Starting ingress")
-
-	listener, err := net.Listen("tcp", ing.addr)
-	if err != nil {
-		logrus.WithError(err).WithFields(logrus.Fields{
-			"addr": ing.addr,
-		}).Fatal("Failed to start ingress")
+	wg.Add(1)
+	for _, l := range ing.listeners {
+		if err := l.start(); err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"listener": l.Name,
+				"addr":     l.Addr,
+			}).Fatal("Failed to start ingress")
+		}
+		// connections outlive the context until they are drained
+		go ing.acceptConnections(ing.relayContext, l)
 	}
-	ing.listener = listener
+	ing.Status = "up"
 
-	logrus.WithFields(logrus.Fields{
-		"addr": ing.addr,
-	}).Info("Started ingress")
-	ing.acceptConnections(context, wg, listener)
+	logrus.WithField("listeners", len(ing.listeners)).Info("Started ingress")
+	go ing.scaleDownIdle(context)
+	go ing.pruneStatusCache(context)
+	<-context.Done()
 }
 
 // Stop the ingress
 func (ing *Ingress) Stop(wg *sync.WaitGroup) {
-	logrus.WithFields(logrus.Fields{
-		"addr": ing.addr,
-	}).Info("Stopping ingress")
+	logrus.Info("Stopping ingress")
 
-	if err := ing.listener.Close(); err != nil {
-		logrus.WithFields(logrus.Fields{
-			"addr": ing.addr,
-		}).Error("Failed to stop ingress")
+	ing.Status = "draining"
+	for _, l := range ing.listeners {
+		l.stop()
 	}
+	ing.drain()
+	ing.cancelRelays()
 
 	ing.Status = "down"
+	close(ing.drained)
 	wg.Done()
-	logrus.WithFields(logrus.Fields{
-		"addr": ing.addr,
-	}).Info("Stopped ingress")
+	logrus.Info("Stopped ingress")
 }
 
-func (ing *Ingress) acceptConnections(context context.Context, wg *sync.WaitGroup, listener net.Listener) {
-	ing.Status = "up"
-	wg.Add(1)
+// Listeners returns the listeners of the ingress.
+func (ing *Ingress) Listeners() []*Listener {
+	return ing.listeners
+}
 
-	go func() {
-		for ing.Status == "up" {
-			connection, err := listener.Accept()
-			if err != nil {
-				logrus.WithError(err).WithFields(logrus.Fields{
-					"addr": ing.addr,
-				}).Error("Failed to accept connection")
-			} else {
-				go ing.handleConnection(context, connection)
-			}
-		}
-	}()
+func (ing *Ingress) handleConnection(context context.Context, l *Listener, client net.Conn) {
+	defer client.Close()
+	defer atomic.AddInt64(&ing.active, -1)
+	atomic.AddInt64(&ing.active, 1)
+	defer metrics.ListenerConnections.With(prometheus.Labels{"listener": l.label()}).Dec()
+	metrics.ListenerConnections.With(prometheus.Labels{"listener": l.label()}).Inc()
 
-	for {
-		select {
-		case <-context.Done():
+	if err := client.SetReadDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		logrus.WithError(err).WithField("client", client.RemoteAddr()).Error("setting deadline failed")
+		return
+	}
+	if ing.acceptProxyProtocol {
+		conn, err := proxyproto.Accept(client, ing.proxyProtocolTrustedNetworks)
+		if err != nil {
+			logrus.WithError(err).WithField("client", client.RemoteAddr()).Error("reading PROXY protocol header failed")
+			metrics.ErrorsTotal.With(prometheus.Labels{"error": "ProxyProtocolFailed"}).Inc()
 			return
 		}
+		client = conn
 	}
-}
-
-func (ing *Ingress) handleConnection(context context.Context, client net.Conn) {
-	defer client.Close()
 	defer logrus.WithField("client", client.RemoteAddr()).Info("closed client connection")
 	logrus.WithField("client", client.RemoteAddr()).Info("inbound client connection")
 
 	buffer := new(bytes.Buffer)
-	reader := io.TeeReader(client, buffer)
+	reader := bufio.NewReader(io.TeeReader(client, buffer))
 
-	if err := client.SetReadDeadline(time.Now().Add(handshakeTimeout)); err != nil {
-		logrus.WithError(err).WithField("client", client.RemoteAddr()).Error("setting deadline failed")
-		return
-	}
 	packet, err := proto.ReadPacket(reader, client.RemoteAddr(), ing.state)
 	if err != nil {
 		logrus.WithError(err).WithField("client", client.RemoteAddr()).Error("reading packet failed")
@@ -143,8 +231,22 @@ func (ing *Ingress) handleConnection(context context.Context, client net.Conn) {
 			"handshake": handshake,
 		}).Debug("decoded handshake")
 
-		hostname := handshake.ServerAddress
-		ing.findAndConnectBackend(context, client, buffer, hostname, "handshake")
+		req := &request{
+			packet:          "handshake",
+			listener:        l,
+			hostname:        handshake.ServerAddress,
+			protocolVersion: handshake.ProtocolVersion,
+			nextState:       handshake.NextState,
+			handshake:       handshake,
+			handshakeSize:   packet.Size(),
+		}
+		if req.nextState == proto.StateLogin {
+			if req.player, err = ing.readLoginStart(client, reader, req); err != nil {
+				logrus.WithError(err).WithField("client", client.RemoteAddr()).Error("reading loginStart packet failed")
+				return
+			}
+		}
+		ing.findAndConnectBackend(context, client, reader, buffer, req)
 	} else if packet.PacketID == proto.LegacyServerListPingID {
 		handshake, ok := packet.Data.(*proto.LegacyServerListPing)
 		if !ok {
@@ -157,8 +259,14 @@ func (ing *Ingress) handleConnection(context context.Context, client net.Conn) {
 			"handshake": handshake.ServerAddress,
 		}).Debug("decoded legacyServerListPing")
 
-		hostname := handshake.ServerAddress
-		ing.findAndConnectBackend(context, client, buffer, hostname, "legacyServerListPing")
+		ing.findAndConnectBackend(context, client, reader, buffer, &request{
+			packet:          "legacyServerListPing",
+			legacy:          true,
+			listener:        l,
+			hostname:        handshake.ServerAddress,
+			protocolVersion: handshake.ProtocolVersion,
+			nextState:       proto.StateStatus,
+		})
 	} else {
 		logrus.WithFields(logrus.Fields{
 			"client":   client.RemoteAddr(),
@@ -168,34 +276,99 @@ func (ing *Ingress) handleConnection(context context.Context, client net.Conn) {
 	}
 }
 
-func (ing *Ingress) findAndConnectBackend(context context.Context, client net.Conn, preReadContent io.Reader, hostname string, packet string) {
-	route, err := routing.FindBackend(hostname)
+func (ing *Ingress) findAndConnectBackend(context context.Context, client net.Conn, reader *bufio.Reader, preReadContent io.Reader, req *request) {
+	route, err := routing.FindListenerRoute(req.listener.Name, req.hostname)
 	if err != nil {
-		logrus.WithError(err).Warn("no matching route found")
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"client":   client.RemoteAddr(),
+			"listener": req.listener.Name,
+			"hostname": req.hostname,
+		}).Warn("no matching route found")
 		metrics.ErrorsTotal.With(prometheus.Labels{"error": "NotFound"}).Inc()
+		ing.respond(client, reader, req, &proto.Status{
+			Version:     proto.StatusVersion{Name: ing.notFoundVersion, Protocol: -1},
+			Description: proto.Chat{Text: ing.notFoundMOTD},
+		}, proto.Chat{Text: ing.notFoundMessage})
+		return
+	}
+	if req.nextState == proto.StateStatus && route.ServesStatus() {
+		ing.respondBackendStatus(context, client, reader, req, route)
+		return
+	}
+	backend, err := route.SelectBackend()
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"client":   client.RemoteAddr(),
+			"hostname": req.hostname,
+		}).Warn("no backend available")
+		metrics.ErrorsTotal.With(prometheus.Labels{"error": "NoBackend"}).Inc()
+		ing.handleOffline(context, client, reader, req, route)
 		return
 	}
 	logrus.WithFields(logrus.Fields{
-		"client": client.RemoteAddr(),
-		"route":  route,
+		"client":   client.RemoteAddr(),
+		"route":    backend,
+		"strategy": route.Strategy,
 	}).Debug("found matching route")
 
-	upstream, err := net.Dial("tcp", route)
+	upstream, err := net.DialTimeout("tcp", backend, dialTimeout)
 	if err != nil {
-		logrus.WithFields(logrus.Fields{
+		logrus.WithError(err).WithFields(logrus.Fields{
 			"client": client.RemoteAddr(),
-			"route":  route,
+			"route":  backend,
 		}).Error("connecting to upstream failed")
 		metrics.ErrorsTotal.With(prometheus.Labels{"error": "UpstreamConnectionFailed"}).Inc()
+		ing.handleOffline(context, client, reader, req, route)
 		return
 	}
-	defer metrics.Connections.With(prometheus.Labels{"route": route}).Dec()
-	metrics.Connections.With(prometheus.Labels{"route": route}).Inc()
+	defer upstream.Close()
+	defer routing.Disconnect(backend)
+	routing.Connect(backend)
+	if route.Workload != nil {
+		defer ing.idle.disconnect(route.Workload)
+		// mark the workload active right away, so other ingresses do not scale it down before the next idle check
+		if ing.idle.connect(route.Workload) && route.IdleTimeout > 0 {
+			go ing.markActive(context, route.Workload)
+		}
+	}
+	if req.nextState == proto.StateLogin {
+		defer metrics.Players.With(prometheus.Labels{"hostname": route.Frontend}).Dec()
+		metrics.Players.With(prometheus.Labels{"hostname": route.Frontend}).Inc()
+	}
 	logrus.WithFields(logrus.Fields{
 		"client":   client.RemoteAddr(),
 		"upstream": upstream.RemoteAddr(),
+		"player":   req.playerName(),
 	}).Info("connected to upstream")
 
+	if route.ProxyProtocol != proxyproto.None {
+		if err := proxyproto.WriteHeader(upstream, route.ProxyProtocol, client.RemoteAddr(), client.LocalAddr()); err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"client":   client.RemoteAddr(),
+				"upstream": upstream.RemoteAddr(),
+			}).Error("failed to send PROXY protocol header to upstream")
+			return
+		}
+		logrus.WithFields(logrus.Fields{
+			"client":   client.RemoteAddr(),
+			"upstream": upstream.RemoteAddr(),
+			"version":  route.ProxyProtocol,
+		}).Debug("sent PROXY protocol header to upstream")
+	}
+
+	handshake, err := ing.upstreamHandshake(client, req, route, backend)
+	if err == nil && handshake != nil {
+		preReadContent, err = replaceHandshake(client, req, preReadContent, handshake)
+	}
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"client":   client.RemoteAddr(),
+			"upstream": upstream.RemoteAddr(),
+		}).Error("rewriting handshake failed")
+		metrics.ErrorsTotal.With(prometheus.Labels{"error": "RewriteHandshakeFailed"}).Inc()
+		return
+	}
+
 	amount, err := io.Copy(upstream, preReadContent)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
@@ -208,7 +381,7 @@ func (ing *Ingress) findAndConnectBackend(context context.Context, client net.Co
 		"client":   client.RemoteAddr(),
 		"upstream": upstream.RemoteAddr(),
 		"amount":   amount,
-	}).Debugf("relayed %s to upstream", packet)
+	}).Debugf("relayed %s to upstream", req.packet)
 
 	if err = client.SetReadDeadline(noDeadline); err != nil {
 		logrus.WithError(err).WithFields(logrus.Fields{
@@ -217,11 +390,102 @@ func (ing *Ingress) findAndConnectBackend(context context.Context, client net.Co
 		}).Error("clearing deadline failed")
 		return
 	}
-	ing.relayConnections(context, route, client, upstream)
+	if req.nextState == proto.StateLogin && route.Forwarding == forwarding.Velocity && ing.forwardingAllowed(client, route) {
+		if upstream, err = ing.forwardVelocity(context, client, upstream, req, route); err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"client": client.RemoteAddr(),
+				"route":  backend,
+			}).Error("forwarding player with velocity failed")
+			metrics.ErrorsTotal.With(prometheus.Labels{"error": "ForwardingFailed"}).Inc()
+			return
+		}
+	}
+
+	connection := Connection{
+		Client:          client.RemoteAddr().String(),
+		Listener:        req.listener.Name,
+		Hostname:        req.hostname,
+		Backend:         backend,
+		ProtocolVersion: req.protocolVersion,
+	}
+	if req.player != nil {
+		connection.Player = req.player.Name
+		connection.PlayerUUID = req.player.UUID
+	}
+	relayContext, relayed := ing.connections.register(context, connection)
+	defer ing.connections.unregister(relayed)
+	ing.relayConnections(relayContext, relayed, client, upstream)
 	return
 }
 
-func (ing *Ingress) relayConnections(context context.Context, route string, client net.Conn, upstream net.Conn) {
+// readLoginStart reads the LoginStart packet following the handshake of a login, the packet is buffered and replayed
+// to the upstream like the handshake. Unknown layouts are not an error, the player is just unknown then.
+func (ing *Ingress) readLoginStart(client net.Conn, reader *bufio.Reader, req *request) (*proto.LoginStart, error) {
+	packet, err := proto.ReadPacket(reader, client.RemoteAddr(), proto.StateLogin)
+	if err != nil {
+		return nil, err
+	}
+	if packet.PacketID != proto.LoginStartID {
+		logrus.WithFields(logrus.Fields{
+			"client":   client.RemoteAddr(),
+			"packetID": packet.PacketID,
+		}).Warn("received unexpected packet, expected loginStart")
+		return nil, nil
+	}
+
+	loginStart, err := proto.ReadLoginStart(packet.Data, req.protocolVersion)
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"client":          client.RemoteAddr(),
+			"protocolVersion": req.protocolVersion,
+		}).Warn("decoding loginStart packet failed")
+		metrics.ErrorsTotal.With(prometheus.Labels{"error": "DecodeLoginStartFailed"}).Inc()
+		return nil, nil
+	}
+	logrus.WithFields(logrus.Fields{
+		"client": client.RemoteAddr(),
+		"player": loginStart.Name,
+		"uuid":   loginStart.UUID,
+	}).Debug("decoded loginStart")
+	return loginStart, nil
+}
+
+// playerName returns the name of the player or an empty string if it is unknown.
+func (req *request) playerName() string {
+	if req.player == nil {
+		return ""
+	}
+	return req.player.Name
+}
+
+// handleOffline wakes up the workload of the route on login and answers the client with the offline response.
+func (ing *Ingress) handleOffline(context context.Context, client net.Conn, reader *bufio.Reader, req *request, route routing.Route) {
+	if req.nextState == proto.StateLogin && route.Workload != nil {
+		ing.scaleUp(context, client, route.Workload)
+	}
+	ing.respondOffline(client, reader, req, route)
+}
+
+func (ing *Ingress) scaleUp(ctx context.Context, client net.Conn, workload *routing.Workload) {
+	ctx, cancel := context.WithTimeout(ctx, workloadTimeout)
+	defer cancel()
+	scaled, err := ing.k8s.ScaleUp(ctx, workload)
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"client":   client.RemoteAddr(),
+			"workload": workload,
+		}).Error("scaling up workload failed")
+		metrics.ErrorsTotal.With(prometheus.Labels{"error": "ScaleUpFailed"}).Inc()
+		return
+	}
+	logrus.WithFields(logrus.Fields{
+		"client":   client.RemoteAddr(),
+		"workload": workload,
+		"scaled":   scaled,
+	}).Debug("woke up workload")
+}
+
+func (ing *Ingress) relayConnections(context context.Context, relayed *relayedConnection, client net.Conn, upstream net.Conn) {
 	defer upstream.Close()
 	defer logrus.WithFields(logrus.Fields{
 		"client":   client.RemoteAddr(),
@@ -233,8 +497,8 @@ func (ing *Ingress) relayConnections(context context.Context, route string, clie
 	}).Debug("relaying connections")
 
 	errors := make(chan error, 2)
-	go ing.relay(upstream, client, errors, "upstream", route)
-	go ing.relay(client, upstream, errors, "downstream", route)
+	go ing.relay(newCountingWriter(upstream, &relayed.bytesUpstream, "upstream", relayed.Backend), upstream, client, errors, "upstream")
+	go ing.relay(newCountingWriter(client, &relayed.bytesDownstream, "downstream", relayed.Backend), client, upstream, errors, "downstream")
 
 	select {
 	case err := <-errors:
@@ -250,14 +514,14 @@ func (ing *Ingress) relayConnections(context context.Context, route string, clie
 	}
 }
 
-func (ing *Ingress) relay(dst net.Conn, src net.Conn, errors chan<- error, direction string, route string) {
+func (ing *Ingress) relay(writer io.Writer, dst net.Conn, src net.Conn, errors chan<- error, direction string) {
 	logrus.WithFields(logrus.Fields{
 		"dst":       dst.RemoteAddr(),
 		"src":       src.RemoteAddr(),
 		"direction": direction,
 	}).Debug("relaying connection")
 
-	bytes, err := io.Copy(dst, src)
+	bytes, err := io.Copy(writer, src)
 	logrus.WithFields(logrus.Fields{
 		"dst":       dst.RemoteAddr(),
 		"src":       src.RemoteAddr(),
@@ -0,0 +1,104 @@
+package ingress
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/qumine/ingress-controller/internal/metrics"
+	"github.com/qumine/ingress-controller/pkg/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Listener represents a single address the ingress accepts connections on.
+type Listener struct {
+	// Name is the name of the listener, named listeners only serve routes with a matching listener.
+	Name string
+	// Addr is the address the listener listens on.
+	Addr string
+
+	// status is the current status of the listener, read concurrently by the health checks.
+	status   atomic.Value
+	listener net.Listener
+}
+
+func newListener(listenerOptions config.ListenerOptions) *Listener {
+	l := &Listener{
+		Name: listenerOptions.Name,
+		Addr: listenerOptions.Address,
+	}
+	l.status.Store("down")
+	return l
+}
+
+// Status returns the current status of the listener.
+func (l *Listener) Status() string {
+	return l.status.Load().(string)
+}
+
+// label returns the name of the listener used in metrics.
+func (l *Listener) label() string {
+	if l.Name != "" {
+		return l.Name
+	}
+	return l.Addr
+}
+
+func (l *Listener) start() error {
+	logrus.WithFields(logrus.Fields{
+		"listener": l.Name,
+		"addr":     l.Addr,
+	}).Debug("Starting listener")
+
+	listener, err := net.Listen("tcp", l.Addr)
+	if err != nil {
+		return err
+	}
+	l.listener = listener
+	l.status.Store("up")
+	metrics.Listeners.With(prometheus.Labels{"listener": l.label()}).Set(1)
+
+	logrus.WithFields(logrus.Fields{
+		"listener": l.Name,
+		"addr":     l.Addr,
+	}).Info("Started listener")
+	return nil
+}
+
+func (l *Listener) stop() {
+	if l.listener == nil {
+		return
+	}
+
+	l.status.Store("down")
+	metrics.Listeners.With(prometheus.Labels{"listener": l.label()}).Set(0)
+	if err := l.listener.Close(); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"listener": l.Name,
+			"addr":     l.Addr,
+		}).Error("Failed to stop listener")
+	}
+	logrus.WithFields(logrus.Fields{
+		"listener": l.Name,
+		"addr":     l.Addr,
+	}).Info("Stopped listener")
+}
+
+func (ing *Ingress) acceptConnections(context context.Context, l *Listener) {
+	for {
+		connection, err := l.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"listener": l.Name,
+				"addr":     l.Addr,
+			}).Error("Failed to accept connection")
+			continue
+		}
+		go ing.handleConnection(context, l, connection)
+	}
+}
@@ -0,0 +1,31 @@
+package ingress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qumine/ingress-controller/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenerStop(t *testing.T) {
+	l := newListener(config.ListenerOptions{Name: "test", Address: "127.0.0.1:0"})
+	assert.Equal(t, "down", l.Status())
+	require.NoError(t, l.start())
+	assert.Equal(t, "up", l.Status())
+
+	stopped := make(chan struct{})
+	go func() {
+		(&Ingress{}).acceptConnections(t.Context(), l)
+		close(stopped)
+	}()
+	l.stop()
+	assert.Equal(t, "down", l.Status())
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("accepting connections did not stop")
+	}
+}
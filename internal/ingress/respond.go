@@ -0,0 +1,104 @@
+package ingress
+
+import (
+	"bufio"
+	"net"
+	"time"
+
+	"github.com/qumine/ingress-controller/internal/proto"
+	"github.com/qumine/ingress-controller/internal/routing"
+	"github.com/sirupsen/logrus"
+)
+
+// respond answers the request of the client without an upstream, with the status for status requests or
+// the disconnect reason for login requests.
+func (ing *Ingress) respond(client net.Conn, reader *bufio.Reader, req *request, status *proto.Status, reason proto.Chat) {
+	if req.legacy {
+		if err := proto.WriteLegacyKick(client, client.RemoteAddr(), status); err != nil {
+			logrus.WithError(err).WithField("client", client.RemoteAddr()).Error("writing legacy kick failed")
+			return
+		}
+		logrus.WithField("client", client.RemoteAddr()).Debug("responded to legacy server list ping")
+		return
+	}
+	if err := client.SetReadDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		logrus.WithError(err).WithField("client", client.RemoteAddr()).Error("setting deadline failed")
+		return
+	}
+
+	switch req.nextState {
+	case proto.StateStatus:
+		ing.respondStatus(client, reader, status)
+	case proto.StateLogin:
+		if err := proto.WriteDisconnect(client, client.RemoteAddr(), reason); err != nil {
+			logrus.WithError(err).WithField("client", client.RemoteAddr()).Error("writing disconnect failed")
+			return
+		}
+		logrus.WithFields(logrus.Fields{
+			"client": client.RemoteAddr(),
+			"reason": reason.Text,
+		}).Debug("disconnected client")
+	}
+}
+
+// respondOffline answers the request of the client for a route with an unreachable upstream.
+func (ing *Ingress) respondOffline(client net.Conn, reader *bufio.Reader, req *request, route routing.Route) {
+	motd := ing.offlineMOTD
+	if route.OfflineMOTD != "" {
+		motd = route.OfflineMOTD
+	}
+	message := ing.offlineMessage
+	if route.OfflineMessage != "" {
+		message = route.OfflineMessage
+	}
+
+	ing.respond(client, reader, req, &proto.Status{
+		Version:     proto.StatusVersion{Name: "Offline", Protocol: -1},
+		Description: proto.Chat{Text: motd},
+	}, proto.Chat{Text: message})
+}
+
+func (ing *Ingress) respondStatus(client net.Conn, reader *bufio.Reader, status *proto.Status) {
+	packet, err := proto.ReadPacket(reader, client.RemoteAddr(), proto.StateStatus)
+	if err != nil {
+		logrus.WithError(err).WithField("client", client.RemoteAddr()).Error("reading status request failed")
+		return
+	}
+	if packet.PacketID != proto.StatusRequestID {
+		logrus.WithFields(logrus.Fields{
+			"client":   client.RemoteAddr(),
+			"packetID": packet.PacketID,
+		}).Error("received unexpected packet, expected statusRequest")
+		return
+	}
+
+	if err := proto.WriteStatusResponse(client, client.RemoteAddr(), status); err != nil {
+		logrus.WithError(err).WithField("client", client.RemoteAddr()).Error("writing status response failed")
+		return
+	}
+	logrus.WithField("client", client.RemoteAddr()).Debug("responded to status request")
+
+	packet, err = proto.ReadPacket(reader, client.RemoteAddr(), proto.StateStatus)
+	if err != nil {
+		logrus.WithError(err).WithField("client", client.RemoteAddr()).Debug("reading ping failed")
+		return
+	}
+	if packet.PacketID != proto.PingID {
+		logrus.WithFields(logrus.Fields{
+			"client":   client.RemoteAddr(),
+			"packetID": packet.PacketID,
+		}).Error("received unexpected packet, expected ping")
+		return
+	}
+
+	payload, err := proto.ReadPing(packet.Data)
+	if err != nil {
+		logrus.WithError(err).WithField("client", client.RemoteAddr()).Error("decoding ping failed")
+		return
+	}
+	if err := proto.WritePong(client, client.RemoteAddr(), payload); err != nil {
+		logrus.WithError(err).WithField("client", client.RemoteAddr()).Error("writing pong failed")
+		return
+	}
+	logrus.WithField("client", client.RemoteAddr()).Debug("responded to ping")
+}
@@ -0,0 +1,268 @@
+package ingress
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/qumine/ingress-controller/internal/metrics"
+	"github.com/qumine/ingress-controller/internal/proto"
+	"github.com/qumine/ingress-controller/internal/proxyproto"
+	"github.com/qumine/ingress-controller/internal/routing"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxStatusSample is the maximum amount of players in the sample of a merged status, like vanilla servers.
+	maxStatusSample = 12
+	// statusFailureTTL is the maximum duration a failed fetch is cached, so a down backend is not dialed by every request.
+	statusFailureTTL = 5 * time.Second
+	// statusCachePruneInterval is the interval expired statuses are removed from the cache.
+	statusCachePruneInterval = 1 * time.Minute
+)
+
+// statusCache caches the status of routes.
+type statusCache struct {
+	mutex   sync.Mutex
+	entries map[string]*statusEntry
+}
+
+type statusEntry struct {
+	// mutex is held while fetching the status, so concurrent requests wait for a single fetch.
+	mutex  sync.Mutex
+	status *proto.Status
+	err    error
+	// protocolVersion is the protocol version of the client the status was fetched for.
+	protocolVersion int
+	expires         time.Time
+}
+
+func newStatusCache() *statusCache {
+	return &statusCache{
+		entries: make(map[string]*statusEntry),
+	}
+}
+
+// get returns the cached status for the key adapted to the protocol version of the client or fetches it if it is
+// missing or expired, failed fetches are cached for at most statusFailureTTL.
+func (c *statusCache) get(key string, ttl time.Duration, protocolVersion int, fetch func() (*proto.Status, error)) (*proto.Status, error) {
+	c.mutex.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &statusEntry{}
+		c.entries[key] = entry
+	}
+	c.mutex.Unlock()
+
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+
+	if time.Now().Before(entry.expires) {
+		metrics.StatusCacheTotal.With(prometheus.Labels{"result": "hit"}).Inc()
+		return adaptProtocolVersion(entry.status, entry.protocolVersion, protocolVersion), entry.err
+	}
+	metrics.StatusCacheTotal.With(prometheus.Labels{"result": "miss"}).Inc()
+
+	entry.status, entry.err = fetch()
+	entry.protocolVersion = protocolVersion
+	if entry.err != nil && ttl > statusFailureTTL {
+		ttl = statusFailureTTL
+	}
+	entry.expires = time.Now().Add(ttl)
+	return entry.status, entry.err
+}
+
+// adaptProtocolVersion returns the status for a client with the protocol version. Servers supporting multiple versions,
+// e.g. with ViaVersion, answer with the protocol version of the client, so it is replaced if the status has the
+// protocol version of the client it was fetched for.
+func adaptProtocolVersion(status *proto.Status, fetchedFor int, protocolVersion int) *proto.Status {
+	if status == nil || fetchedFor == protocolVersion || status.Version.Protocol != fetchedFor {
+		return status
+	}
+	adapted := *status
+	adapted.Version.Protocol = protocolVersion
+	return &adapted
+}
+
+// prune removes the expired statuses, including the ones of removed routes, statuses being fetched are kept.
+func (c *statusCache) prune(now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key, entry := range c.entries {
+		if !entry.mutex.TryLock() {
+			continue
+		}
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+		}
+		entry.mutex.Unlock()
+	}
+}
+
+// pruneStatusCache periodically removes expired statuses from the cache.
+func (ing *Ingress) pruneStatusCache(context context.Context) {
+	ticker := time.NewTicker(statusCachePruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-context.Done():
+			return
+		case now := <-ticker.C:
+			ing.statusCache.prune(now)
+		}
+	}
+}
+
+// respondBackendStatus answers the status request of the client with the status of the backends of the route,
+// which is cached if the route has a status cache TTL.
+func (ing *Ingress) respondBackendStatus(context context.Context, client net.Conn, reader *bufio.Reader, req *request, route routing.Route) {
+	var status *proto.Status
+	var err error
+	if route.StatusCacheTTL > 0 {
+		key := route.Source.UID + "/" + route.Frontend
+		status, err = ing.statusCache.get(key, route.StatusCacheTTL, req.protocolVersion, func() (*proto.Status, error) {
+			return ing.routeStatus(req, route)
+		})
+	} else {
+		status, err = ing.routeStatus(req, route)
+	}
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"client":   client.RemoteAddr(),
+			"hostname": req.hostname,
+		}).Warn("fetching status failed")
+		metrics.ErrorsTotal.With(prometheus.Labels{"error": "StatusFetchFailed"}).Inc()
+		ing.handleOffline(context, client, reader, req, route)
+		return
+	}
+
+	ing.respond(client, reader, req, status, proto.Chat{})
+}
+
+// routeStatus fetches the status of a backend of the route or the merged status of all of them if the route
+// aggregates the status. The MOTD and favicon are replaced by the ones of the route.
+func (ing *Ingress) routeStatus(req *request, route routing.Route) (*proto.Status, error) {
+	var status *proto.Status
+	if route.StatusAggregate {
+		statuses := ing.fetchStatuses(route.Backends, req, route)
+		if len(statuses) == 0 {
+			return nil, errors.New("fetching status of all backends failed")
+		}
+		status = mergeStatus(statuses)
+	} else {
+		backend, err := route.SelectBackend()
+		if err != nil {
+			return nil, err
+		}
+		if status, err = ing.fetchStatus(backend, req, route); err != nil {
+			return nil, err
+		}
+	}
+
+	if route.StatusMOTD != "" {
+		status.Description = proto.Chat{Text: route.StatusMOTD}
+	}
+	if route.StatusFavicon != "" {
+		status.Favicon = route.StatusFavicon
+	}
+	return status, nil
+}
+
+// fetchStatuses fetches the status of all backends concurrently and returns the successfully fetched ones in
+// the order of the backends.
+func (ing *Ingress) fetchStatuses(backends []string, req *request, route routing.Route) []*proto.Status {
+	results := make([]*proto.Status, len(backends))
+	wg := &sync.WaitGroup{}
+	for i, backend := range backends {
+		wg.Add(1)
+		go func(i int, backend string) {
+			defer wg.Done()
+			status, err := ing.fetchStatus(backend, req, route)
+			if err != nil {
+				logrus.WithError(err).WithField("backend", backend).Debug("fetching status of backend failed")
+				return
+			}
+			results[i] = status
+		}(i, backend)
+	}
+	wg.Wait()
+
+	statuses := make([]*proto.Status, 0, len(results))
+	for _, status := range results {
+		if status != nil {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// mergeStatus merges the players of the statuses, the version, MOTD and favicon are taken from the first status.
+func mergeStatus(statuses []*proto.Status) *proto.Status {
+	merged := *statuses[0]
+	merged.Players = proto.StatusPlayers{}
+	for _, status := range statuses {
+		merged.Players.Online += status.Players.Online
+		merged.Players.Max += status.Players.Max
+		for _, sample := range status.Players.Sample {
+			if len(merged.Players.Sample) < maxStatusSample {
+				merged.Players.Sample = append(merged.Players.Sample, sample)
+			}
+		}
+	}
+	return &merged
+}
+
+// fetchStatus requests the status from the backend like a client would.
+func (ing *Ingress) fetchStatus(backend string, req *request, route routing.Route) (*proto.Status, error) {
+	upstream, err := net.DialTimeout("tcp", backend, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer upstream.Close()
+	if err := upstream.SetDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		return nil, err
+	}
+
+	if route.ProxyProtocol != proxyproto.None {
+		if err := proxyproto.WriteHeader(upstream, route.ProxyProtocol, upstream.LocalAddr(), upstream.RemoteAddr()); err != nil {
+			return nil, err
+		}
+	}
+
+	handshake := proto.Handshake{ProtocolVersion: req.protocolVersion, ServerAddress: req.hostname}
+	if req.handshake != nil {
+		handshake = *req.handshake
+	}
+	handshake.NextState = proto.StateStatus
+	if route.Rewrite != nil {
+		if err := rewriteHandshake(&handshake, route.Rewrite, backend); err != nil {
+			return nil, err
+		}
+	}
+	if err := proto.WriteHandshake(upstream, upstream.RemoteAddr(), &handshake); err != nil {
+		return nil, err
+	}
+	if err := proto.WriteStatusRequest(upstream, upstream.RemoteAddr()); err != nil {
+		return nil, err
+	}
+
+	packet, err := proto.ReadPacket(bufio.NewReader(upstream), upstream.RemoteAddr(), proto.StateStatus)
+	if err != nil {
+		return nil, err
+	}
+	if packet.PacketID != proto.StatusResponseID {
+		return nil, fmt.Errorf("received unexpected packet %d, expected statusResponse", packet.PacketID)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"backend": backend,
+	}).Debug("fetched status")
+	return proto.ReadStatusResponse(packet.Data)
+}
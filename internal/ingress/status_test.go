@@ -0,0 +1,193 @@
+package ingress
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/qumine/ingress-controller/internal/proto"
+	"github.com/qumine/ingress-controller/internal/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusCache(t *testing.T) {
+	cache := newStatusCache()
+	fetches := 0
+	fetch := func() (*proto.Status, error) {
+		fetches++
+		return &proto.Status{Players: proto.StatusPlayers{Online: fetches}}, nil
+	}
+
+	status, err := cache.get("route", time.Hour, 763, fetch)
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.Players.Online)
+
+	status, err = cache.get("route", time.Hour, 763, fetch)
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.Players.Online)
+
+	status, err = cache.get("expired", 0, 763, fetch)
+	require.NoError(t, err)
+	assert.Equal(t, 2, status.Players.Online)
+
+	status, err = cache.get("expired", 0, 763, fetch)
+	require.NoError(t, err)
+	assert.Equal(t, 3, status.Players.Online)
+
+	failures := 0
+	fail := func() (*proto.Status, error) {
+		failures++
+		return nil, errors.New("failed")
+	}
+	_, err = cache.get("failed", time.Hour, 763, fail)
+	assert.Error(t, err)
+	_, err = cache.get("failed", time.Hour, 763, fail)
+	assert.Error(t, err)
+	assert.Equal(t, 1, failures)
+	assert.WithinDuration(t, time.Now().Add(statusFailureTTL), cache.entries["failed"].expires, time.Second)
+}
+
+func TestStatusCacheProtocolVersion(t *testing.T) {
+	cache := newStatusCache()
+	fetches := 0
+	fetch := func(protocol int) func() (*proto.Status, error) {
+		return func() (*proto.Status, error) {
+			fetches++
+			return &proto.Status{Version: proto.StatusVersion{Name: "1.20.1", Protocol: protocol}}, nil
+		}
+	}
+
+	// a server supporting multiple versions answers with the protocol version of the client
+	status, err := cache.get("multiple", time.Hour, 763, fetch(763))
+	require.NoError(t, err)
+	assert.Equal(t, 763, status.Version.Protocol)
+	status, err = cache.get("multiple", time.Hour, 759, fetch(759))
+	require.NoError(t, err)
+	assert.Equal(t, 759, status.Version.Protocol)
+
+	// a server supporting a single version answers with its own protocol version
+	status, err = cache.get("single", time.Hour, 759, fetch(763))
+	require.NoError(t, err)
+	assert.Equal(t, 763, status.Version.Protocol)
+	status, err = cache.get("single", time.Hour, 760, fetch(763))
+	require.NoError(t, err)
+	assert.Equal(t, 763, status.Version.Protocol)
+
+	assert.Equal(t, 2, fetches)
+	assert.Len(t, cache.entries, 2)
+}
+
+func TestStatusCachePrune(t *testing.T) {
+	cache := newStatusCache()
+	fetch := func() (*proto.Status, error) { return &proto.Status{}, nil }
+
+	_, err := cache.get("route", time.Hour, 763, fetch)
+	require.NoError(t, err)
+	_, err = cache.get("expired", time.Millisecond, 763, fetch)
+	require.NoError(t, err)
+
+	cache.prune(time.Now().Add(time.Minute))
+	assert.Contains(t, cache.entries, "route")
+	assert.NotContains(t, cache.entries, "expired")
+
+	cache.prune(time.Now().Add(2 * time.Hour))
+	assert.Empty(t, cache.entries)
+}
+
+// serveStatus starts a fake backend answering a single status request with the status after the delay.
+func serveStatus(t *testing.T, status *proto.Status, delay time.Duration) (string, <-chan *proto.Handshake) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	handshakes := make(chan *proto.Handshake, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		packet, err := proto.ReadPacket(reader, nil, proto.StateHandshaking)
+		if err != nil {
+			return
+		}
+		handshake, _ := proto.ReadHandshake(packet.Data)
+		handshakes <- handshake
+		if _, err := proto.ReadPacket(reader, nil, proto.StateStatus); err != nil {
+			return
+		}
+		time.Sleep(delay)
+		proto.WriteStatusResponse(conn, nil, status)
+	}()
+	return listener.Addr().String(), handshakes
+}
+
+func TestFetchStatus(t *testing.T) {
+	expected := &proto.Status{
+		Version:     proto.StatusVersion{Name: "1.20.1", Protocol: 763},
+		Players:     proto.StatusPlayers{Max: 20, Online: 3},
+		Description: map[string]interface{}{"text": "A Minecraft Server"},
+	}
+	backend, handshakes := serveStatus(t, expected, 0)
+
+	ing := &Ingress{}
+	status, err := ing.fetchStatus(backend, &request{
+		handshake: &proto.Handshake{ProtocolVersion: 763, ServerAddress: "mc.example.com", ServerPort: 25565, NextState: proto.StateStatus},
+	}, routing.NewRoute("mc.example.com"))
+	require.NoError(t, err)
+	assert.Equal(t, expected, status)
+	assert.Equal(t, "mc.example.com", (<-handshakes).ServerAddress)
+}
+
+func TestFetchStatuses(t *testing.T) {
+	lobby := &proto.Status{
+		Version:     proto.StatusVersion{Name: "1.20.1", Protocol: 763},
+		Players:     proto.StatusPlayers{Max: 20, Online: 2},
+		Description: map[string]interface{}{"text": "Lobby"},
+	}
+	survival := &proto.Status{
+		Version:     proto.StatusVersion{Name: "1.20.1", Protocol: 763},
+		Players:     proto.StatusPlayers{Max: 50, Online: 1},
+		Description: map[string]interface{}{"text": "Survival"},
+	}
+	// the first backend answers last to ensure the statuses are ordered by the backends
+	first, _ := serveStatus(t, lobby, 100*time.Millisecond)
+	second, _ := serveStatus(t, survival, 0)
+	failing, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	failing.Close()
+
+	ing := &Ingress{}
+	statuses := ing.fetchStatuses([]string{first, failing.Addr().String(), second}, &request{
+		handshake: &proto.Handshake{ProtocolVersion: 763, ServerAddress: "mc.example.com", ServerPort: 25565, NextState: proto.StateStatus},
+	}, routing.NewRoute("mc.example.com"))
+	assert.Equal(t, []*proto.Status{lobby, survival}, statuses)
+}
+
+func TestMergeStatus(t *testing.T) {
+	merged := mergeStatus([]*proto.Status{
+		{
+			Version:     proto.StatusVersion{Name: "1.20.1", Protocol: 763},
+			Players:     proto.StatusPlayers{Max: 20, Online: 2, Sample: []proto.StatusPlayerSample{{Name: "Notch"}, {Name: "jeb_"}}},
+			Description: proto.Chat{Text: "Lobby"},
+			Favicon:     "data:image/png;base64,lobby",
+		},
+		{
+			Version:     proto.StatusVersion{Name: "1.20.2", Protocol: 764},
+			Players:     proto.StatusPlayers{Max: 50, Online: 1, Sample: []proto.StatusPlayerSample{{Name: "Dinnerbone"}}},
+			Description: proto.Chat{Text: "Survival"},
+		},
+	})
+
+	assert.Equal(t, &proto.Status{
+		Version:     proto.StatusVersion{Name: "1.20.1", Protocol: 763},
+		Players:     proto.StatusPlayers{Max: 70, Online: 3, Sample: []proto.StatusPlayerSample{{Name: "Notch"}, {Name: "jeb_"}, {Name: "Dinnerbone"}}},
+		Description: proto.Chat{Text: "Lobby"},
+		Favicon:     "data:image/png;base64,lobby",
+	}, merged)
+}
@@ -2,68 +2,416 @@ package k8s
 
 import (
 	"net"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/qumine/ingress-controller/internal/forwarding"
 	"github.com/qumine/ingress-controller/internal/metrics"
+	"github.com/qumine/ingress-controller/internal/proxyproto"
 	"github.com/qumine/ingress-controller/internal/routing"
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
 )
 
-func onAdd(obj interface{}) {
+func (k8s *K8S) onAdd(obj interface{}) {
+	k8s.touch()
 	service, ok := obj.(*v1.Service)
 	if !ok {
 		metrics.ErrorsTotal.With(prometheus.Labels{"error": "InternalError"}).Inc()
 		return
 	}
 
-	hostname := "localhost"
-	if h, exists := service.Annotations[AnnotationHostname]; exists {
-		hostname = h
-	} else {
+	if !isIngressService(service) {
 		logrus.WithFields(logrus.Fields{
 			"service": service,
-		}).Tracef("Adding service skipped, %s annotation not present", AnnotationHostname)
+		}).Tracef("Adding service skipped, %s or %s annotation not present", AnnotationHostname, AnnotationDefault)
 		return
 	}
 
-	portname := "minecraft"
-	if p, exists := service.Annotations[AnnotationPortname]; exists {
-		portname = p
+	for key, route := range k8s.newRoutes(service) {
+		routing.Add(key, route)
+	}
+}
+
+func (k8s *K8S) onDelete(obj interface{}) {
+	k8s.touch()
+	service, ok := obj.(*v1.Service)
+	if !ok {
+		metrics.ErrorsTotal.With(prometheus.Labels{"error": "InternalError"}).Inc()
+		return
+	}
+
+	if !isIngressService(service) {
+		logrus.WithFields(logrus.Fields{
+			"service": service,
+		}).Tracef("Deleting service skipped, %s or %s annotation not present", AnnotationHostname, AnnotationDefault)
+		return
+	}
+
+	for _, key := range routeKeys(service) {
+		routing.Remove(key)
+	}
+}
+
+// onUpdate updates the routes of the service in place, so the routing never misses the service and conflicts
+// are only reported for new losers. Routes of removed hostnames are removed.
+func (k8s *K8S) onUpdate(oldObj interface{}, newObj interface{}) {
+	k8s.touch()
+	oldService, ok := oldObj.(*v1.Service)
+	if !ok {
+		metrics.ErrorsTotal.With(prometheus.Labels{"error": "InternalError"}).Inc()
+		return
+	}
+	newService, ok := newObj.(*v1.Service)
+	if !ok {
+		metrics.ErrorsTotal.With(prometheus.Labels{"error": "InternalError"}).Inc()
+		return
+	}
+
+	routes := make(map[string]routing.Route)
+	if isIngressService(newService) {
+		routes = k8s.newRoutes(newService)
+	}
+	if isIngressService(oldService) {
+		for _, key := range routeKeys(oldService) {
+			if _, exists := routes[key]; !exists {
+				routing.Remove(key)
+			}
+		}
+	}
+	for key, route := range routes {
+		routing.Update(key, route)
+	}
+}
+
+// onEndpointSliceChange updates the route of the service owning the endpoint slice.
+func (k8s *K8S) onEndpointSliceChange(obj interface{}) {
+	k8s.touch()
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	endpointSlice, ok := obj.(*discoveryv1.EndpointSlice)
+	if !ok {
+		metrics.ErrorsTotal.With(prometheus.Labels{"error": "InternalError"}).Inc()
+		return
+	}
+
+	serviceName, exists := endpointSlice.Labels[discoveryv1.LabelServiceName]
+	if !exists {
+		return
+	}
+	serviceObj, exists, err := k8s.services.GetByKey(endpointSlice.Namespace + "/" + serviceName)
+	if err != nil || !exists {
+		return
+	}
+	service, ok := serviceObj.(*v1.Service)
+	if !ok || !isIngressService(service) {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"service": service.Name,
+	}).Debug("Updating route endpoints")
+	for key, route := range k8s.newRoutes(service) {
+		routing.Update(key, route)
+	}
+}
+
+// newRoutes creates the routes for all hostnames of the service by their key. A default service has a single
+// default route without hostname, routed to the port of its first hostname.
+func (k8s *K8S) newRoutes(service *v1.Service) map[string]routing.Route {
+	targets := routeTargets(service)
+
+	routes := make(map[string]routing.Route)
+	for _, target := range targets {
+		if target.hostname == "" {
+			continue
+		}
+		if route, ok := k8s.newRoute(service, target, false); ok {
+			routes[routeKey(service, target.hostname)] = route
+		}
 	}
+	if isDefaultService(service) {
+		if route, ok := k8s.newRoute(service, routeTarget{portname: targets[0].portname}, true); ok {
+			routes[routeKey(service, "")] = route
+		}
+	}
+	return routes
+}
+
+// newRoute creates the route for the target of the service.
+func (k8s *K8S) newRoute(service *v1.Service, target routeTarget, isDefault bool) (routing.Route, bool) {
 	logrus.WithFields(logrus.Fields{
-		"hostname": hostname,
-		"portname": portname,
+		"hostname": target.hostname,
+		"portname": target.portname,
+		"default":  isDefault,
 	}).Debug("Adding route")
 
+	backends, ok := k8s.backends(service, target.portname)
+	if !ok {
+		return routing.Route{}, false
+	}
+
+	route := routing.NewRoute(target.hostname, backends...)
+	if isDefault {
+		route = routing.NewDefaultRoute(target.hostname, backends...)
+	}
+	route.Source = routing.Source{
+		Namespace: service.Namespace,
+		Name:      service.Name,
+		UID:       string(service.UID),
+	}
+	route.Created = service.CreationTimestamp.Time
+	applyAnnotations(service, &route)
+	return route, true
+}
+
+// backends returns the backends of the service for the given port.
+func (k8s *K8S) backends(service *v1.Service, portname string) ([]string, bool) {
 	for _, p := range service.Spec.Ports {
-		if p.Name == portname {
-			routing.Add(string(service.UID), routing.NewRoute(hostname, net.JoinHostPort(service.Spec.ClusterIP, strconv.Itoa(int(p.Port)))))
-			return
+		if p.Name != portname {
+			continue
+		}
+
+		if k8s.endpointRouting {
+			return k8s.endpointBackends(service, portname), true
 		}
+		if service.Spec.ClusterIP == "" || service.Spec.ClusterIP == v1.ClusterIPNone {
+			logrus.WithField("service", service.Name).Warn("Adding route skipped, headless services require endpoint routing")
+			metrics.ErrorsTotal.With(prometheus.Labels{"error": "HeadlessService"}).Inc()
+			return nil, false
+		}
+		return []string{net.JoinHostPort(service.Spec.ClusterIP, strconv.Itoa(int(p.Port)))}, true
 	}
 	metrics.ErrorsTotal.With(prometheus.Labels{"error": "NoMatchingPort"}).Inc()
+	return nil, false
 }
 
-func onDelete(obj interface{}) {
-	service, ok := obj.(*v1.Service)
-	if !ok {
-		metrics.ErrorsTotal.With(prometheus.Labels{"error": "InternalError"}).Inc()
-		return
+// endpointBackends returns the addresses of the ready endpoints of the service for the given port.
+func (k8s *K8S) endpointBackends(service *v1.Service, portname string) []string {
+	endpointSlices, err := k8s.endpointSlices.ByIndex(indexService, service.Namespace+"/"+service.Name)
+	if err != nil {
+		logrus.WithError(err).WithField("service", service.Name).Error("Listing endpoint slices failed")
+		return nil
 	}
 
-	if _, exists := service.Annotations[AnnotationHostname]; !exists {
-		logrus.WithFields(logrus.Fields{
-			"service": service,
-		}).Tracef("Deleting service skipped, %s annotation not present", AnnotationHostname)
+	backends := make([]string, 0)
+	for _, obj := range endpointSlices {
+		endpointSlice, ok := obj.(*discoveryv1.EndpointSlice)
+		if !ok {
+			continue
+		}
+
+		var port int32
+		for _, p := range endpointSlice.Ports {
+			if p.Name != nil && *p.Name == portname && p.Port != nil {
+				port = *p.Port
+			}
+		}
+		if port == 0 {
+			continue
+		}
+
+		for _, endpoint := range endpointSlice.Endpoints {
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+			for _, address := range endpoint.Addresses {
+				backends = append(backends, net.JoinHostPort(address, strconv.Itoa(int(port))))
+			}
+		}
+	}
+	sort.Strings(backends)
+	return backends
+}
+
+// applyAnnotations applies the optional annotations of the service to the route.
+func applyAnnotations(service *v1.Service, route *routing.Route) {
+	route.OfflineMOTD = service.Annotations[AnnotationOfflineMOTD]
+	route.OfflineMessage = service.Annotations[AnnotationOfflineMessage]
+	if w, exists := service.Annotations[AnnotationWorkload]; exists {
+		workload, err := ParseWorkload(service.Namespace, w)
+		if err != nil {
+			logrus.WithError(err).WithField("service", service.Name).Warnf("Ignoring %s annotation", AnnotationWorkload)
+			metrics.ErrorsTotal.With(prometheus.Labels{"error": "InvalidWorkload"}).Inc()
+		}
+		route.Workload = workload
+	}
+	if t, exists := service.Annotations[AnnotationIdleTimeout]; exists {
+		idleTimeout, err := time.ParseDuration(t)
+		if err != nil {
+			logrus.WithError(err).WithField("service", service.Name).Warnf("Ignoring %s annotation", AnnotationIdleTimeout)
+			metrics.ErrorsTotal.With(prometheus.Labels{"error": "InvalidIdleTimeout"}).Inc()
+		}
+		route.IdleTimeout = idleTimeout
+	}
+	if v, exists := service.Annotations[AnnotationProxyProtocol]; exists {
+		version, err := proxyproto.ParseVersion(v)
+		if err != nil {
+			logrus.WithError(err).WithField("service", service.Name).Warnf("Ignoring %s annotation", AnnotationProxyProtocol)
+			metrics.ErrorsTotal.With(prometheus.Labels{"error": "InvalidProxyProtocol"}).Inc()
+		}
+		route.ProxyProtocol = version
+	}
+	if st, exists := service.Annotations[AnnotationStrategy]; exists {
+		strategy, err := routing.ParseStrategy(st)
+		if err != nil {
+			logrus.WithError(err).WithField("service", service.Name).Warnf("Ignoring %s annotation", AnnotationStrategy)
+			metrics.ErrorsTotal.With(prometheus.Labels{"error": "InvalidStrategy"}).Inc()
+		} else {
+			route.Strategy = strategy
+		}
+	}
+	if t, exists := service.Annotations[AnnotationStatusCacheTTL]; exists {
+		ttl, err := time.ParseDuration(t)
+		if err != nil {
+			logrus.WithError(err).WithField("service", service.Name).Warnf("Ignoring %s annotation", AnnotationStatusCacheTTL)
+			metrics.ErrorsTotal.With(prometheus.Labels{"error": "InvalidStatusCacheTTL"}).Inc()
+		}
+		route.StatusCacheTTL = ttl
+	}
+	if a, exists := service.Annotations[AnnotationStatusAggregate]; exists {
+		aggregate, err := strconv.ParseBool(a)
+		if err != nil {
+			logrus.WithError(err).WithField("service", service.Name).Warnf("Ignoring %s annotation", AnnotationStatusAggregate)
+			metrics.ErrorsTotal.With(prometheus.Labels{"error": "InvalidStatusAggregate"}).Inc()
+		}
+		route.StatusAggregate = aggregate
+	}
+	route.StatusMOTD = service.Annotations[AnnotationStatusMOTD]
+	route.StatusFavicon = service.Annotations[AnnotationStatusFavicon]
+	if r, exists := service.Annotations[AnnotationRewrite]; exists {
+		rewrite, err := routing.ParseRewrite(r)
+		if err != nil {
+			logrus.WithError(err).WithField("service", service.Name).Warnf("Ignoring %s annotation", AnnotationRewrite)
+			metrics.ErrorsTotal.With(prometheus.Labels{"error": "InvalidRewrite"}).Inc()
+		}
+		route.Rewrite = rewrite
+	}
+	if f, exists := service.Annotations[AnnotationForwarding]; exists {
+		mode, err := forwarding.ParseMode(f)
+		if err != nil {
+			logrus.WithError(err).WithField("service", service.Name).Warnf("Ignoring %s annotation", AnnotationForwarding)
+			metrics.ErrorsTotal.With(prometheus.Labels{"error": "InvalidForwarding"}).Inc()
+		}
+		route.Forwarding = mode
+	}
+	if s, exists := service.Annotations[AnnotationForwardingSecret]; exists {
+		secret, err := ParseSecret(service.Namespace, s)
+		if err != nil {
+			logrus.WithError(err).WithField("service", service.Name).Warnf("Ignoring %s annotation", AnnotationForwardingSecret)
+			metrics.ErrorsTotal.With(prometheus.Labels{"error": "InvalidForwardingSecret"}).Inc()
+		}
+		route.ForwardingSecret = secret
+	}
+	route.Pool = service.Annotations[AnnotationPool]
+	for _, listener := range strings.Split(service.Annotations[AnnotationListener], ",") {
+		if listener = strings.TrimSpace(listener); listener != "" {
+			route.Listeners = append(route.Listeners, listener)
+		}
+	}
+	if p, exists := service.Annotations[AnnotationPriority]; exists {
+		priority, err := strconv.Atoi(p)
+		if err != nil {
+			logrus.WithError(err).WithField("service", service.Name).Warnf("Ignoring %s annotation", AnnotationPriority)
+			metrics.ErrorsTotal.With(prometheus.Labels{"error": "InvalidPriority"}).Inc()
+		}
+		route.Priority = priority
+	}
+}
+
+// onConflict records an event on the service losing a hostname conflict.
+func (k8s *K8S) onConflict(conflict routing.Conflict) {
+	if k8s.recorder == nil {
 		return
 	}
 
-	routing.Remove(string(service.UID))
+	k8s.recorder.Eventf(&v1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Service",
+		Namespace:  conflict.Loser.Source.Namespace,
+		Name:       conflict.Loser.Source.Name,
+		UID:        types.UID(conflict.Loser.Source.UID),
+	}, v1.EventTypeWarning, "HostnameConflict", "Hostname %q is already routed to service %s", conflict.Frontend, conflict.Winner.Source)
+}
+
+// routeTarget is a hostname of a service and the name of the port it is routed to.
+type routeTarget struct {
+	hostname string
+	portname string
 }
 
-func onUpdate(oldObj interface{}, newObj interface{}) {
-	onDelete(oldObj)
-	onAdd(newObj)
+// routeTargets returns the comma separated hostnames of the service, each optionally mapped to a port
+// like hostname=portname. A default service without hostnames has a single empty hostname.
+func routeTargets(service *v1.Service) []routeTarget {
+	portname := "minecraft"
+	if p, exists := service.Annotations[AnnotationPortname]; exists {
+		portname = p
+	}
+
+	result := make([]routeTarget, 0)
+	for _, entry := range strings.Split(service.Annotations[AnnotationHostname], ",") {
+		target := routeTarget{portname: portname}
+		if i := strings.Index(entry, "="); i >= 0 {
+			target.portname = strings.TrimSpace(entry[i+1:])
+			entry = entry[:i]
+		}
+		if target.hostname = strings.TrimSpace(entry); target.hostname != "" {
+			result = append(result, target)
+		}
+	}
+	if len(result) == 0 {
+		result = append(result, routeTarget{portname: portname})
+	}
+	return result
+}
+
+// routeKeys returns the keys of all routes of the service, the default route is keyed by the empty hostname.
+func routeKeys(service *v1.Service) []string {
+	keys := make([]string, 0)
+	for _, target := range routeTargets(service) {
+		if target.hostname != "" {
+			keys = append(keys, routeKey(service, target.hostname))
+		}
+	}
+	if isDefaultService(service) {
+		keys = append(keys, routeKey(service, ""))
+	}
+	return keys
+}
+
+// routeKey returns the key of the route for the hostname of the service.
+func routeKey(service *v1.Service, hostname string) string {
+	return string(service.UID) + "/" + strings.ToLower(hostname)
+}
+
+// isIngressService checks if the service should be routed by the ingress.
+func isIngressService(service *v1.Service) bool {
+	_, exists := service.Annotations[AnnotationHostname]
+	return exists || isDefaultService(service)
+}
+
+// isDefaultService checks if the service is marked as the fallback for unknown hostnames.
+func isDefaultService(service *v1.Service) bool {
+	isDefault, err := strconv.ParseBool(service.Annotations[AnnotationDefault])
+	return err == nil && isDefault
+}
+
+// indexByService indexes endpoint slices by the namespace and name of their service.
+func indexByService(obj interface{}) ([]string, error) {
+	endpointSlice, ok := obj.(*discoveryv1.EndpointSlice)
+	if !ok {
+		return nil, nil
+	}
+	serviceName, exists := endpointSlice.Labels[discoveryv1.LabelServiceName]
+	if !exists {
+		return nil, nil
+	}
+	return []string{endpointSlice.Namespace + "/" + serviceName}, nil
 }
@@ -0,0 +1,191 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qumine/ingress-controller/internal/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestNewRouteEndpointRouting(t *testing.T) {
+	ready, notReady := true, false
+	portname, port := "minecraft", int32(25565)
+
+	k8s := &K8S{
+		endpointRouting: true,
+		endpointSlices:  cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{indexService: indexByService}),
+	}
+	require.NoError(t, k8s.endpointSlices.Add(&discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "survival-abcde",
+			Namespace: "minecraft",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: "survival"},
+		},
+		Ports: []discoveryv1.EndpointPort{{Name: &portname, Port: &port}},
+		Endpoints: []discoveryv1.Endpoint{
+			{Addresses: []string{"10.1.0.2"}, Conditions: discoveryv1.EndpointConditions{Ready: &ready}},
+			{Addresses: []string{"10.1.0.3"}, Conditions: discoveryv1.EndpointConditions{Ready: &notReady}},
+			{Addresses: []string{"10.1.0.1"}},
+		},
+	}))
+
+	routes := k8s.newRoutes(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			UID:         "1234",
+			Name:        "survival",
+			Namespace:   "minecraft",
+			Annotations: map[string]string{AnnotationHostname: "survival.example.com"},
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP: v1.ClusterIPNone,
+			Ports:     []v1.ServicePort{{Name: portname, Port: port}},
+		},
+	})
+	require.Contains(t, routes, "1234/survival.example.com")
+
+	route := routes["1234/survival.example.com"]
+	assert.Equal(t, "survival.example.com", route.Frontend)
+	assert.Equal(t, []string{"10.1.0.1:25565", "10.1.0.2:25565"}, route.Backends)
+}
+
+func TestNewRoutesMultipleHostnames(t *testing.T) {
+	k8s := &K8S{}
+	routes := k8s.newRoutes(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			UID:         "1234",
+			Name:        "survival",
+			Namespace:   "minecraft",
+			Annotations: map[string]string{AnnotationHostname: "mc.example.com, Play.example.com,,legacy.example.org"},
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP: "10.96.0.10",
+			Ports:     []v1.ServicePort{{Name: "minecraft", Port: 25565}},
+		},
+	})
+
+	require.Len(t, routes, 3)
+	for key, frontend := range map[string]string{
+		"1234/mc.example.com":     "mc.example.com",
+		"1234/play.example.com":   "play.example.com",
+		"1234/legacy.example.org": "legacy.example.org",
+	} {
+		require.Contains(t, routes, key)
+		assert.Equal(t, frontend, routes[key].Frontend)
+		assert.Equal(t, []string{"10.96.0.10:25565"}, routes[key].Backends)
+	}
+}
+
+func TestNewRoutesHostnamePorts(t *testing.T) {
+	k8s := &K8S{}
+	routes := k8s.newRoutes(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			UID:         "1234",
+			Name:        "worlds",
+			Namespace:   "minecraft",
+			Annotations: map[string]string{AnnotationHostname: "survival.example.com=survival,creative.example.com=creative,lobby.example.com"},
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP: "10.96.0.10",
+			Ports: []v1.ServicePort{
+				{Name: "minecraft", Port: 25565},
+				{Name: "survival", Port: 25566},
+				{Name: "creative", Port: 25567},
+			},
+		},
+	})
+
+	require.Len(t, routes, 3)
+	assert.Equal(t, []string{"10.96.0.10:25566"}, routes["1234/survival.example.com"].Backends)
+	assert.Equal(t, []string{"10.96.0.10:25567"}, routes["1234/creative.example.com"].Backends)
+	assert.Equal(t, []string{"10.96.0.10:25565"}, routes["1234/lobby.example.com"].Backends)
+}
+
+func TestNewRoutesDefault(t *testing.T) {
+	k8s := &K8S{}
+	routes := k8s.newRoutes(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			UID:       "1234",
+			Name:      "worlds",
+			Namespace: "minecraft",
+			Annotations: map[string]string{
+				AnnotationDefault:  "true",
+				AnnotationHostname: "survival.example.com=survival,creative.example.com=creative",
+			},
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP: "10.96.0.10",
+			Ports: []v1.ServicePort{
+				{Name: "survival", Port: 25566},
+				{Name: "creative", Port: 25567},
+			},
+		},
+	})
+
+	require.Len(t, routes, 3)
+	assert.False(t, routes["1234/survival.example.com"].Default)
+	assert.False(t, routes["1234/creative.example.com"].Default)
+	require.Contains(t, routes, "1234/")
+	assert.True(t, routes["1234/"].Default)
+	assert.Equal(t, "", routes["1234/"].Frontend)
+	assert.Equal(t, []string{"10.96.0.10:25566"}, routes["1234/"].Backends)
+
+	router := routing.NewRouter()
+	conflicts := make([]routing.Conflict, 0)
+	router.OnConflict(func(conflict routing.Conflict) { conflicts = append(conflicts, conflict) })
+	for key, route := range routes {
+		router.Add(key, route)
+	}
+	assert.Empty(t, conflicts)
+	assert.Equal(t, routing.MatchDefault, router.Resolve("", "unknown.example.com").Match)
+}
+
+func TestOnUpdate(t *testing.T) {
+	conflicts := make([]routing.Conflict, 0)
+	routing.OnConflict(func(conflict routing.Conflict) { conflicts = append(conflicts, conflict) })
+	defer routing.OnConflict(nil)
+
+	newService := func(uid string, hostname string, created time.Time) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				UID:               types.UID(uid),
+				Name:              uid,
+				Namespace:         "minecraft",
+				CreationTimestamp: metav1.NewTime(created),
+				Annotations:       map[string]string{AnnotationHostname: hostname},
+			},
+			Spec: v1.ServiceSpec{
+				ClusterIP: "10.96.0." + uid,
+				Ports:     []v1.ServicePort{{Name: "minecraft", Port: 25565}},
+			},
+		}
+	}
+	winner := newService("1", "update.example.com", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	loser := newService("2", "update.example.com", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	k8s := &K8S{}
+	k8s.onAdd(winner)
+	k8s.onAdd(loser)
+	defer k8s.onDelete(winner)
+	require.Len(t, conflicts, 1)
+
+	for i := 0; i < 3; i++ {
+		k8s.onUpdate(winner, winner)
+		k8s.onUpdate(loser, loser)
+	}
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, []string{"10.96.0.1:25565"}, routing.Resolve("", "update.example.com").Route.Backends)
+
+	moved := newService("2", "moved.example.com", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	k8s.onUpdate(loser, moved)
+	defer k8s.onDelete(moved)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, []string{"10.96.0.1:25565"}, routing.Resolve("", "update.example.com").Route.Backends)
+	assert.Equal(t, []string{"10.96.0.2:25565"}, routing.Resolve("", "moved.example.com").Route.Backends)
+}
@@ -3,14 +3,21 @@ package k8s
 import (
 	"context"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/qumine/ingress-controller/internal/routing"
 	"github.com/qumine/ingress-controller/pkg/config"
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
@@ -18,6 +25,45 @@ const (
 	AnnotationHostname = "ingress.qumine.io/hostname"
 	// AnnotationPortname is the kubernetes annotation for the name of the port to use
 	AnnotationPortname = "ingress.qumine.io/portname"
+	// AnnotationDefault is the kubernetes annotation to mark the service as fallback for unknown hostnames
+	AnnotationDefault = "ingress.qumine.io/default"
+	// AnnotationOfflineMOTD is the kubernetes annotation for the MOTD shown while the service is unreachable
+	AnnotationOfflineMOTD = "ingress.qumine.io/offline-motd"
+	// AnnotationOfflineMessage is the kubernetes annotation for the disconnect message while the service is unreachable
+	AnnotationOfflineMessage = "ingress.qumine.io/offline-message"
+	// AnnotationWorkload is the kubernetes annotation for the workload to scale up on login, in the form of kind/name
+	AnnotationWorkload = "ingress.qumine.io/workload"
+	// AnnotationIdleTimeout is the kubernetes annotation for the duration without connections after which the workload is scaled down
+	AnnotationIdleTimeout = "ingress.qumine.io/idle-timeout"
+	// AnnotationLastActive is the kubernetes annotation set on workloads with the last time a connection was active
+	AnnotationLastActive = "ingress.qumine.io/last-active"
+	// AnnotationProxyProtocol is the kubernetes annotation for the PROXY protocol version to send to the service
+	AnnotationProxyProtocol = "ingress.qumine.io/proxy-protocol"
+	// AnnotationStrategy is the kubernetes annotation for the strategy to select one of the backends of a hostname
+	AnnotationStrategy = "ingress.qumine.io/strategy"
+	// AnnotationPool is the kubernetes annotation for the pool of services sharing a hostname
+	AnnotationPool = "ingress.qumine.io/pool"
+	// AnnotationPriority is the kubernetes annotation for the priority of the service if services conflict on a hostname
+	AnnotationPriority = "ingress.qumine.io/priority"
+	// AnnotationStatusCacheTTL is the kubernetes annotation for the duration the status of the service is cached
+	AnnotationStatusCacheTTL = "ingress.qumine.io/status-cache-ttl"
+	// AnnotationStatusAggregate is the kubernetes annotation to merge the status of all backends of the service
+	AnnotationStatusAggregate = "ingress.qumine.io/status-aggregate"
+	// AnnotationStatusMOTD is the kubernetes annotation for the MOTD replacing the one of the status of the service
+	AnnotationStatusMOTD = "ingress.qumine.io/status-motd"
+	// AnnotationStatusFavicon is the kubernetes annotation for the favicon replacing the one of the status of the service
+	AnnotationStatusFavicon = "ingress.qumine.io/status-favicon"
+	// AnnotationRewrite is the kubernetes annotation for the address the handshake is rewritten to, backend, host, host:port or :port
+	AnnotationRewrite = "ingress.qumine.io/rewrite"
+	// AnnotationForwarding is the kubernetes annotation for the mode to forward the identity of players, bungeecord or velocity
+	AnnotationForwarding = "ingress.qumine.io/forwarding"
+	// AnnotationForwardingSecret is the kubernetes annotation for the secret used for velocity forwarding, in the form of name[/key]
+	AnnotationForwardingSecret = "ingress.qumine.io/forwarding-secret"
+	// AnnotationListener is the kubernetes annotation for the comma separated named listeners serving the service
+	AnnotationListener = "ingress.qumine.io/listener"
+
+	indexService   = "service"
+	eventComponent = "qumine-ingress-controller"
 )
 
 // K8S is a watcher for kubernetes
@@ -25,15 +71,29 @@ type K8S struct {
 	// Status is the current status of the K8S watcher.
 	Status string
 
-	kubeconfig string
-	stop       chan struct{}
+	kubeconfig      string
+	endpointRouting bool
+	clientset       kubernetes.Interface
+	broadcaster     record.EventBroadcaster
+	recorder        record.EventRecorder
+	services        cache.Store
+	endpointSlices  cache.Indexer
+	secrets         *secretCache
+	stop            chan struct{}
+
+	// synced is set once the initial list of the informers is synced.
+	synced atomic.Bool
+	// lastEvent is the time of the last informer event in unix nanoseconds.
+	lastEvent atomic.Int64
 }
 
 // NewK8S creates a new k8s instance
 func NewK8S(k8sOptions config.K8SOptions) *K8S {
 	return &K8S{
-		kubeconfig: k8sOptions.KubeConfig,
-		stop:       make(chan struct{}, 1),
+		kubeconfig:      k8sOptions.KubeConfig,
+		endpointRouting: k8sOptions.EndpointRouting,
+		secrets:         newSecretCache(),
+		stop:            make(chan struct{}),
 	}
 }
 
@@ -58,6 +118,12 @@ func (k8s *K8S) Start(context context.Context, wg *sync.WaitGroup) {
 		}).Fatal("Failed to start K8S")
 	}
 
+	k8s.clientset = clientset
+	k8s.broadcaster = record.NewBroadcaster()
+	k8s.broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events(v1.NamespaceAll)})
+	k8s.recorder = k8s.broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: eventComponent})
+	routing.OnConflict(k8s.onConflict)
+
 	watchlist := cache.NewListWatchFromClient(
 		clientset.CoreV1().RESTClient(),
 		string(v1.ResourceServices),
@@ -65,20 +131,59 @@ func (k8s *K8S) Start(context context.Context, wg *sync.WaitGroup) {
 		fields.Everything(),
 	)
 
-	_, controller := cache.NewInformer(
+	services, controller := cache.NewInformer(
 		watchlist,
 		&v1.Service{},
 		0,
 		cache.ResourceEventHandlerFuncs{
-			AddFunc:    onAdd,
-			DeleteFunc: onDelete,
-			UpdateFunc: onUpdate,
+			AddFunc:    k8s.onAdd,
+			DeleteFunc: k8s.onDelete,
+			UpdateFunc: k8s.onUpdate,
 		},
 	)
+	k8s.services = services
+
+	if k8s.endpointRouting {
+		endpointSliceWatchlist := cache.NewListWatchFromClient(
+			clientset.DiscoveryV1().RESTClient(),
+			"endpointslices",
+			v1.NamespaceAll,
+			fields.Everything(),
+		)
+
+		endpointSlices, endpointSliceController := cache.NewIndexerInformer(
+			endpointSliceWatchlist,
+			&discoveryv1.EndpointSlice{},
+			0,
+			cache.ResourceEventHandlerFuncs{
+				AddFunc:    k8s.onEndpointSliceChange,
+				DeleteFunc: k8s.onEndpointSliceChange,
+				UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+					k8s.onEndpointSliceChange(newObj)
+				},
+			},
+			cache.Indexers{indexService: indexByService},
+		)
+		k8s.endpointSlices = endpointSlices
+
+		go endpointSliceController.Run(k8s.stop)
+		if !cache.WaitForCacheSync(k8s.stop, endpointSliceController.HasSynced) {
+			logrus.WithFields(logrus.Fields{
+				"kubeconfig": k8s.kubeconfig,
+			}).Fatal("Failed to sync endpoint slices")
+		}
+	}
 
+	wg.Add(1)
 	go controller.Run(k8s.stop)
+	if !cache.WaitForCacheSync(context.Done(), controller.HasSynced) {
+		logrus.WithFields(logrus.Fields{
+			"kubeconfig": k8s.kubeconfig,
+		}).Warn("Stopped K8S before services synced")
+		return
+	}
+	k8s.synced.Store(true)
 	k8s.Status = "up"
-	wg.Add(1)
 
 	logrus.WithFields(logrus.Fields{
 		"kubeconfig": k8s.kubeconfig,
@@ -92,13 +197,35 @@ func (k8s *K8S) Start(context context.Context, wg *sync.WaitGroup) {
 	}
 }
 
+// Synced returns whether the initial list of the informers is synced.
+func (k8s *K8S) Synced() bool {
+	return k8s.synced.Load()
+}
+
+// LastEvent returns the time of the last informer event, the zero time if there was none.
+func (k8s *K8S) LastEvent() time.Time {
+	lastEvent := k8s.lastEvent.Load()
+	if lastEvent == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, lastEvent)
+}
+
+// touch records an informer event.
+func (k8s *K8S) touch() {
+	k8s.lastEvent.Store(time.Now().UnixNano())
+}
+
 // Stop the K8S
 func (k8s *K8S) Stop(wg *sync.WaitGroup) {
 	logrus.WithFields(logrus.Fields{
 		"kubeconfig": k8s.kubeconfig,
 	}).Info("Stopping K8S")
 
-	k8s.stop <- struct{}{}
+	close(k8s.stop)
+	if k8s.broadcaster != nil {
+		k8s.broadcaster.Shutdown()
+	}
 
 	k8s.Status = "down"
 	wg.Done()
@@ -0,0 +1,183 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/qumine/ingress-controller/internal/metrics"
+	"github.com/qumine/ingress-controller/internal/routing"
+	"github.com/sirupsen/logrus"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// WorkloadKindDeployment is the kind of a deployment workload.
+	WorkloadKindDeployment = "deployment"
+	// WorkloadKindStatefulSet is the kind of a statefulset workload.
+	WorkloadKindStatefulSet = "statefulset"
+)
+
+// ParseWorkload parses a workload reference in the form of kind/name.
+func ParseWorkload(namespace string, reference string) (*routing.Workload, error) {
+	parts := strings.SplitN(reference, "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("invalid workload reference %q, expected kind/name", reference)
+	}
+
+	kind := strings.ToLower(parts[0])
+	if kind != WorkloadKindDeployment && kind != WorkloadKindStatefulSet {
+		return nil, fmt.Errorf("unsupported workload kind %q", parts[0])
+	}
+	return &routing.Workload{
+		Namespace: namespace,
+		Kind:      kind,
+		Name:      parts[1],
+	}, nil
+}
+
+// ScaleUp scales the workload to a single replica if it is scaled to zero and reports whether it was scaled.
+func (k8s *K8S) ScaleUp(ctx context.Context, workload *routing.Workload) (bool, error) {
+	scale, err := k8s.getScale(ctx, workload)
+	if err != nil {
+		return false, err
+	}
+	if scale.Spec.Replicas > 0 {
+		return false, nil
+	}
+
+	if err := k8s.MarkActive(ctx, workload); err != nil {
+		return false, err
+	}
+	scale.Spec.Replicas = 1
+	if err := k8s.updateScale(ctx, workload, scale); err != nil {
+		return false, err
+	}
+	logrus.WithField("workload", workload).Info("scaled up workload")
+	metrics.ScalesTotal.With(prometheus.Labels{"direction": "up", "workload": workload.String()}).Inc()
+	return true, nil
+}
+
+// ScaleDown scales the workload to zero replicas if no ingress marked it active within the idle timeout
+// and reports whether it was scaled.
+func (k8s *K8S) ScaleDown(ctx context.Context, workload *routing.Workload, idleTimeout time.Duration) (bool, error) {
+	meta, err := k8s.getObjectMeta(ctx, workload)
+	if err != nil {
+		return false, err
+	}
+	if lastActive, err := time.Parse(time.RFC3339, meta.Annotations[AnnotationLastActive]); err == nil && time.Since(lastActive) < idleTimeout {
+		return false, nil
+	}
+
+	scale, err := k8s.getScale(ctx, workload)
+	if err != nil {
+		return false, err
+	}
+	if scale.Spec.Replicas == 0 {
+		return false, nil
+	}
+	// the workload changed since its last active annotation was checked
+	if scale.ResourceVersion != meta.ResourceVersion {
+		return false, nil
+	}
+
+	// the resource version of the scale is a precondition of the update, so the update fails if another ingress
+	// marked the workload active in the meantime
+	scale.Spec.Replicas = 0
+	if err := k8s.updateScale(ctx, workload, scale); err != nil {
+		if apierrors.IsConflict(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"workload":    workload,
+		"idleTimeout": idleTimeout,
+	}).Info("scaled down idle workload")
+	metrics.ScalesTotal.With(prometheus.Labels{"direction": "down", "workload": workload.String()}).Inc()
+	return true, nil
+}
+
+// MarkActive marks the workload as active, which is shared between all ingresses to prevent a scale down.
+func (k8s *K8S) MarkActive(ctx context.Context, workload *routing.Workload) error {
+	if k8s.clientset == nil {
+		return errors.New("k8s not started")
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				AnnotationLastActive: time.Now().UTC().Format(time.RFC3339),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	switch workload.Kind {
+	case WorkloadKindDeployment:
+		_, err = k8s.clientset.AppsV1().Deployments(workload.Namespace).Patch(ctx, workload.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	case WorkloadKindStatefulSet:
+		_, err = k8s.clientset.AppsV1().StatefulSets(workload.Namespace).Patch(ctx, workload.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	default:
+		err = fmt.Errorf("unsupported workload kind %q", workload.Kind)
+	}
+	return err
+}
+
+func (k8s *K8S) getObjectMeta(ctx context.Context, workload *routing.Workload) (*metav1.ObjectMeta, error) {
+	if k8s.clientset == nil {
+		return nil, errors.New("k8s not started")
+	}
+
+	switch workload.Kind {
+	case WorkloadKindDeployment:
+		deployment, err := k8s.clientset.AppsV1().Deployments(workload.Namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &deployment.ObjectMeta, nil
+	case WorkloadKindStatefulSet:
+		statefulSet, err := k8s.clientset.AppsV1().StatefulSets(workload.Namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &statefulSet.ObjectMeta, nil
+	}
+	return nil, fmt.Errorf("unsupported workload kind %q", workload.Kind)
+}
+
+func (k8s *K8S) getScale(ctx context.Context, workload *routing.Workload) (*autoscalingv1.Scale, error) {
+	if k8s.clientset == nil {
+		return nil, errors.New("k8s not started")
+	}
+
+	switch workload.Kind {
+	case WorkloadKindDeployment:
+		return k8s.clientset.AppsV1().Deployments(workload.Namespace).GetScale(ctx, workload.Name, metav1.GetOptions{})
+	case WorkloadKindStatefulSet:
+		return k8s.clientset.AppsV1().StatefulSets(workload.Namespace).GetScale(ctx, workload.Name, metav1.GetOptions{})
+	}
+	return nil, fmt.Errorf("unsupported workload kind %q", workload.Kind)
+}
+
+func (k8s *K8S) updateScale(ctx context.Context, workload *routing.Workload, scale *autoscalingv1.Scale) error {
+	var err error
+	switch workload.Kind {
+	case WorkloadKindDeployment:
+		_, err = k8s.clientset.AppsV1().Deployments(workload.Namespace).UpdateScale(ctx, workload.Name, scale, metav1.UpdateOptions{})
+	case WorkloadKindStatefulSet:
+		_, err = k8s.clientset.AppsV1().StatefulSets(workload.Namespace).UpdateScale(ctx, workload.Name, scale, metav1.UpdateOptions{})
+	default:
+		err = fmt.Errorf("unsupported workload kind %q", workload.Kind)
+	}
+	return err
+}
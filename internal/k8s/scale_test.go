@@ -0,0 +1,46 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/qumine/ingress-controller/internal/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWorkload(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Input    string
+		Expected *routing.Workload
+	}{
+		{
+			Name:     "StatefulSet",
+			Input:    "statefulset/survival",
+			Expected: &routing.Workload{Namespace: "minecraft", Kind: WorkloadKindStatefulSet, Name: "survival"},
+		},
+		{
+			Name:     "Deployment",
+			Input:    "Deployment/lobby",
+			Expected: &routing.Workload{Namespace: "minecraft", Kind: WorkloadKindDeployment, Name: "lobby"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			result, err := ParseWorkload("minecraft", tt.Input)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.Expected, result)
+		})
+	}
+}
+
+func TestParseWorkloadInvalid(t *testing.T) {
+	for _, reference := range []string{"survival", "statefulset/", "daemonset/survival"} {
+		t.Run(reference, func(t *testing.T) {
+			_, err := ParseWorkload("minecraft", reference)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qumine/ingress-controller/internal/routing"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SecretKeyDefault is the key of the secret used if the reference does not contain a key.
+	SecretKeyDefault = "forwarding-secret"
+
+	// secretCacheTTL is the duration a fetched secret is used before it is fetched again.
+	secretCacheTTL = 1 * time.Minute
+	// secretTimeout is the timeout for fetching a secret.
+	secretTimeout = 5 * time.Second
+)
+
+// secretCache caches the values of secrets, so logins do not request the secret from the API server.
+type secretCache struct {
+	mutex   sync.Mutex
+	entries map[string]cachedSecret
+}
+
+type cachedSecret struct {
+	value   []byte
+	expires time.Time
+}
+
+func newSecretCache() *secretCache {
+	return &secretCache{
+		entries: make(map[string]cachedSecret),
+	}
+}
+
+func (c *secretCache) get(key string, now time.Time) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expires) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (c *secretCache) set(key string, value []byte, now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries[key] = cachedSecret{value: value, expires: now.Add(secretCacheTTL)}
+}
+
+// ParseSecret parses a secret reference in the form of name[/key].
+func ParseSecret(namespace string, reference string) (*routing.Secret, error) {
+	parts := strings.SplitN(reference, "/", 2)
+	if parts[0] == "" {
+		return nil, fmt.Errorf("invalid secret reference %q, expected name[/key]", reference)
+	}
+
+	secret := &routing.Secret{
+		Namespace: namespace,
+		Name:      parts[0],
+		Key:       SecretKeyDefault,
+	}
+	if len(parts) == 2 && parts[1] != "" {
+		secret.Key = parts[1]
+	}
+	return secret, nil
+}
+
+// GetSecret returns the value of the referenced secret key without surrounding whitespace, values are cached for
+// secretCacheTTL.
+func (k8s *K8S) GetSecret(ctx context.Context, secret *routing.Secret) ([]byte, error) {
+	if k8s.clientset == nil {
+		return nil, errors.New("k8s not started")
+	}
+	if value, ok := k8s.secrets.get(secret.String(), time.Now()); ok {
+		return value, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, secretTimeout)
+	defer cancel()
+	object, err := k8s.clientset.CoreV1().Secrets(secret.Namespace).Get(ctx, secret.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	value, exists := object.Data[secret.Key]
+	if !exists {
+		return nil, fmt.Errorf("secret %s has no key %q", secret.Name, secret.Key)
+	}
+	trimmed := []byte(strings.TrimSpace(string(value)))
+	k8s.secrets.set(secret.String(), trimmed, time.Now())
+	return trimmed, nil
+}
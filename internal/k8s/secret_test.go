@@ -0,0 +1,75 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/qumine/ingress-controller/internal/routing"
+	"github.com/qumine/ingress-controller/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseSecret(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Input    string
+		Expected *routing.Secret
+	}{
+		{
+			Name:     "Default key",
+			Input:    "velocity",
+			Expected: &routing.Secret{Namespace: "minecraft", Name: "velocity", Key: SecretKeyDefault},
+		},
+		{
+			Name:     "Key",
+			Input:    "velocity/forwarding.secret",
+			Expected: &routing.Secret{Namespace: "minecraft", Name: "velocity", Key: "forwarding.secret"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			result, err := ParseSecret("minecraft", tt.Input)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.Expected, result)
+		})
+	}
+}
+
+func TestParseSecretInvalid(t *testing.T) {
+	_, err := ParseSecret("minecraft", "/forwarding.secret")
+	assert.Error(t, err)
+}
+
+func TestGetSecret(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "minecraft", Name: "velocity"},
+		Data:       map[string][]byte{SecretKeyDefault: []byte("secret\n")},
+	})
+	k8s := &K8S{clientset: clientset, secrets: newSecretCache()}
+	secret := &routing.Secret{Namespace: "minecraft", Name: "velocity", Key: SecretKeyDefault}
+
+	value, err := k8s.GetSecret(context.Background(), secret)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), value)
+
+	// the cached value is used without requesting the secret again
+	require.NoError(t, clientset.CoreV1().Secrets("minecraft").Delete(context.Background(), "velocity", metav1.DeleteOptions{}))
+	value, err = k8s.GetSecret(context.Background(), secret)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), value)
+
+	_, err = k8s.GetSecret(context.Background(), &routing.Secret{Namespace: "minecraft", Name: "velocity", Key: "missing"})
+	assert.Error(t, err)
+}
+
+func TestGetSecretNotStarted(t *testing.T) {
+	k8s := NewK8S(config.K8SOptions{})
+	_, err := k8s.GetSecret(context.Background(), &routing.Secret{Namespace: "minecraft", Name: "velocity", Key: SecretKeyDefault})
+	assert.Error(t, err)
+}
@@ -12,6 +12,13 @@ var (
 			Help: "The amount of registered routes",
 		},
 	)
+	// RouteConflicts represents the metrics for the amount of routes losing a hostname conflict
+	RouteConflicts = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "qumine_ingress_route_conflicts",
+			Help: "The amount of routes losing a hostname conflict",
+		},
+	)
 	// Connections represents the metrics for the amount of active connections
 	Connections = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -20,6 +27,30 @@ var (
 		},
 		[]string{"route"},
 	)
+	// Players represents the metrics for the amount of players connected to a route
+	Players = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "qumine_ingress_players",
+			Help: "The amount of players connected to a route",
+		},
+		[]string{"hostname"},
+	)
+	// Listeners represents the metrics for the status of the listeners
+	Listeners = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "qumine_ingress_listener_up",
+			Help: "Whether the listener is accepting connections",
+		},
+		[]string{"listener"},
+	)
+	// ListenerConnections represents the metrics for the amount of active client connections per listener
+	ListenerConnections = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "qumine_ingress_listener_connections",
+			Help: "The amount of active client connections per listener",
+		},
+		[]string{"listener"},
+	)
 	// ErrorsTotal represents the metrics for the amount of total errors
 	ErrorsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -36,11 +67,33 @@ var (
 		},
 		[]string{"direction", "route"},
 	)
+	// StatusCacheTotal represents the metrics for the amount of total status requests answered by the status cache
+	StatusCacheTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "qumine_ingress_status_cache_total",
+			Help: "The total status requests answered by the status cache",
+		},
+		[]string{"result"},
+	)
+	// ScalesTotal represents the metrics for the amount of total workload scale operations
+	ScalesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "qumine_ingress_scales_total",
+			Help: "The total workload scale operations",
+		},
+		[]string{"direction", "workload"},
+	)
 )
 
 func init() {
 	prometheus.MustRegister(Routes)
+	prometheus.MustRegister(RouteConflicts)
 	prometheus.MustRegister(Connections)
+	prometheus.MustRegister(Players)
+	prometheus.MustRegister(Listeners)
+	prometheus.MustRegister(ListenerConnections)
 	prometheus.MustRegister(ErrorsTotal)
 	prometheus.MustRegister(BytesTotal)
+	prometheus.MustRegister(StatusCacheTotal)
+	prometheus.MustRegister(ScalesTotal)
 }
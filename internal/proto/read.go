@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 	"io"
 	"net"
 	"strings"
@@ -279,3 +281,150 @@ func ReadHandshake(data interface{}) (*Handshake, error) {
 	handshake.NextState = nextState
 	return handshake, nil
 }
+
+// ReadLoginPluginRequest reads a LoginPluginRequest packet from the given data.
+func ReadLoginPluginRequest(data interface{}) (*LoginPluginRequest, error) {
+	dataBytes, ok := data.([]byte)
+	if !ok {
+		return nil, errors.New("data is not expected byte slice")
+	}
+
+	request := &LoginPluginRequest{}
+	buffer := bytes.NewBuffer(dataBytes)
+	var err error
+
+	request.MessageID, err = readVarInt(buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Channel, err = readString(buffer)
+	if err != nil {
+		return nil, err
+	}
+	request.Data = buffer.Bytes()
+	return request, nil
+}
+
+// ReadPing reads the payload of a Ping packet from the given data.
+func ReadPing(data interface{}) (int64, error) {
+	dataBytes, ok := data.([]byte)
+	if !ok {
+		return 0, errors.New("data is not expected byte slice")
+	}
+
+	var payload int64
+	if err := binary.Read(bytes.NewBuffer(dataBytes), binary.BigEndian, &payload); err != nil {
+		return 0, err
+	}
+	return payload, nil
+}
+
+// ReadStatusResponse reads a StatusResponse packet from the given data.
+func ReadStatusResponse(data interface{}) (*Status, error) {
+	dataBytes, ok := data.([]byte)
+	if !ok {
+		return nil, errors.New("data is not expected byte slice")
+	}
+
+	content, err := readString(bytes.NewBuffer(dataBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{}
+	if err := json.Unmarshal([]byte(content), status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// maxByteArrayLength is the maximum length of byte arrays read from a packet.
+const maxByteArrayLength = 4096
+
+// ReadLoginStart reads a LoginStart packet from the given data using the layout of the protocol version.
+func ReadLoginStart(data interface{}, protocolVersion int) (*LoginStart, error) {
+	dataBytes, ok := data.([]byte)
+	if !ok {
+		return nil, errors.New("data is not expected byte slice")
+	}
+
+	loginStart := &LoginStart{}
+	buffer := bytes.NewBuffer(dataBytes)
+	var err error
+
+	loginStart.Name, err = readString(buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	if protocolVersion == ProtocolVersion1_19 || protocolVersion == ProtocolVersion1_19_1 {
+		hasSignature, err := readBool(buffer)
+		if err != nil {
+			return nil, err
+		}
+		if hasSignature {
+			// timestamp, public key and signature
+			if _, err := readBytes(buffer, 8); err != nil {
+				return nil, err
+			}
+			if _, err := readByteArray(buffer); err != nil {
+				return nil, err
+			}
+			if _, err := readByteArray(buffer); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	hasUUID := protocolVersion >= ProtocolVersion1_20_2
+	if protocolVersion >= ProtocolVersion1_19_1 && protocolVersion < ProtocolVersion1_20_2 {
+		hasUUID, err = readBool(buffer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if hasUUID {
+		loginStart.UUID, err = readUUID(buffer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return loginStart, nil
+}
+
+func readBool(reader io.Reader) (bool, error) {
+	value, err := readByte(reader)
+	if err != nil {
+		return false, err
+	}
+	return value != 0x00, nil
+}
+
+func readBytes(reader io.Reader, length int) ([]byte, error) {
+	value := make([]byte, length)
+	if _, err := io.ReadFull(reader, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func readByteArray(reader io.Reader) ([]byte, error) {
+	length, err := readVarInt(reader)
+	if err != nil {
+		return nil, err
+	}
+	if length < 0 || length > maxByteArrayLength {
+		return nil, errors.New("byte array length is invalid")
+	}
+	return readBytes(reader, length)
+}
+
+func readUUID(reader io.Reader) (string, error) {
+	value, err := readBytes(reader, 16)
+	if err != nil {
+		return "", err
+	}
+	uuid := hex.EncodeToString(value)
+	return uuid[0:8] + "-" + uuid[8:12] + "-" + uuid[12:16] + "-" + uuid[16:20] + "-" + uuid[20:32], nil
+}
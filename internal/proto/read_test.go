@@ -35,3 +35,56 @@ func TestReadVarInt(t *testing.T) {
 		})
 	}
 }
+
+func TestReadLoginStart(t *testing.T) {
+	name := []byte{0x05, 'S', 't', 'e', 'v', 'e'}
+	uuid := []byte{0x06, 0x9a, 0x79, 0xf4, 0x44, 0xe9, 0x4a, 0x2c, 0x9a, 0xf1, 0x2a, 0x3e, 0x5e, 0xb1, 0x4c, 0x7b}
+	signature := append(append([]byte{0x01, 0, 0, 0, 0, 0, 0, 0, 0}, 0x02, 0xAA, 0xBB), 0x01, 0xCC)
+
+	tests := []struct {
+		Name            string
+		ProtocolVersion int
+		Input           []byte
+		Expected        LoginStart
+	}{
+		{
+			Name:            "1.18",
+			ProtocolVersion: 758,
+			Input:           name,
+			Expected:        LoginStart{Name: "Steve"},
+		},
+		{
+			Name:            "1.19 with signature",
+			ProtocolVersion: ProtocolVersion1_19,
+			Input:           append(append([]byte{}, name...), signature...),
+			Expected:        LoginStart{Name: "Steve"},
+		},
+		{
+			Name:            "1.19.1 with signature and UUID",
+			ProtocolVersion: ProtocolVersion1_19_1,
+			Input:           append(append(append(append([]byte{}, name...), signature...), 0x01), uuid...),
+			Expected:        LoginStart{Name: "Steve", UUID: "069a79f4-44e9-4a2c-9af1-2a3e5eb14c7b"},
+		},
+		{
+			Name:            "1.19.3 without UUID",
+			ProtocolVersion: ProtocolVersion1_19_3,
+			Input:           append(append([]byte{}, name...), 0x00),
+			Expected:        LoginStart{Name: "Steve"},
+		},
+		{
+			Name:            "1.20.2",
+			ProtocolVersion: ProtocolVersion1_20_2,
+			Input:           append(append([]byte{}, name...), uuid...),
+			Expected:        LoginStart{Name: "Steve", UUID: "069a79f4-44e9-4a2c-9af1-2a3e5eb14c7b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			result, err := ReadLoginStart(tt.Input, tt.ProtocolVersion)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.Expected, *result)
+		})
+	}
+}
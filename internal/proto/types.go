@@ -14,6 +14,10 @@ type State int
 const (
 	// StateHandshaking is the initial state of a minecraft connection.
 	StateHandshaking = iota
+	// StateStatus is the state of a minecraft connection requesting the server status.
+	StateStatus
+	// StateLogin is the state of a minecraft connection logging in.
+	StateLogin
 )
 
 var trimLimit = 64
@@ -40,6 +44,17 @@ type Packet struct {
 	Data interface{}
 }
 
+// Size returns the amount of bytes the packet occupies on the wire including its length.
+func (p *Packet) Size() int {
+	size := p.Length
+	for value := uint32(p.Length); ; value >>= 7 {
+		size++
+		if value < 0x80 {
+			return size
+		}
+	}
+}
+
 func (p *Packet) String() string {
 	if dataBytes, ok := p.Data.([]byte); ok {
 		trimmed, cont := trimBytes(dataBytes)
@@ -54,6 +69,35 @@ const (
 	HandshakeID = 0x00
 	// LegacyServerListPingID is the ID of the LegacyServerListPing packet.
 	LegacyServerListPingID = 0xFE
+	// LegacyKickID is the ID of the kick packet answering a LegacyServerListPing.
+	LegacyKickID = 0xFF
+	// StatusRequestID is the ID of the StatusRequest packet.
+	StatusRequestID = 0x00
+	// StatusResponseID is the ID of the StatusResponse packet.
+	StatusResponseID = 0x00
+	// PingID is the ID of the Ping packet.
+	PingID = 0x01
+	// PongID is the ID of the Pong packet.
+	PongID = 0x01
+	// DisconnectID is the ID of the Disconnect packet during login.
+	DisconnectID = 0x00
+	// LoginStartID is the ID of the LoginStart packet.
+	LoginStartID = 0x00
+	// LoginPluginRequestID is the ID of the LoginPluginRequest packet send by the server.
+	LoginPluginRequestID = 0x04
+	// LoginPluginResponseID is the ID of the LoginPluginResponse packet send by the client.
+	LoginPluginResponseID = 0x02
+)
+
+const (
+	// ProtocolVersion1_19 is the protocol version of 1.19, which added the signature data to LoginStart.
+	ProtocolVersion1_19 = 759
+	// ProtocolVersion1_19_1 is the protocol version of 1.19.1, which added the optional player UUID to LoginStart.
+	ProtocolVersion1_19_1 = 760
+	// ProtocolVersion1_19_3 is the protocol version of 1.19.3, which removed the signature data from LoginStart.
+	ProtocolVersion1_19_3 = 761
+	// ProtocolVersion1_20_2 is the protocol version of 1.20.2, which made the player UUID of LoginStart mandatory.
+	ProtocolVersion1_20_2 = 764
 )
 
 // Handshake is the first packet in the minecraft protocol send by the client.
@@ -71,6 +115,52 @@ type LegacyServerListPing struct {
 	ServerPort      uint16
 }
 
+// LoginStart is the first packet send by the client during login.
+type LoginStart struct {
+	Name string
+	// UUID is the UUID of the player, empty if the client did not send it.
+	UUID string
+}
+
+// LoginPluginRequest is send by the server during login to request custom data of the client.
+type LoginPluginRequest struct {
+	MessageID int
+	Channel   string
+	Data      []byte
+}
+
+// Chat is a minecraft chat component.
+type Chat struct {
+	Text string `json:"text"`
+}
+
+// Status is the server status send in the StatusResponse packet.
+type Status struct {
+	Version     StatusVersion `json:"version"`
+	Players     StatusPlayers `json:"players"`
+	Description interface{}   `json:"description"`
+	Favicon     string        `json:"favicon,omitempty"`
+}
+
+// StatusVersion is the version of the server status.
+type StatusVersion struct {
+	Name     string `json:"name"`
+	Protocol int    `json:"protocol"`
+}
+
+// StatusPlayers are the players of the server status.
+type StatusPlayers struct {
+	Max    int                  `json:"max"`
+	Online int                  `json:"online"`
+	Sample []StatusPlayerSample `json:"sample,omitempty"`
+}
+
+// StatusPlayerSample is a single player shown in the server status.
+type StatusPlayerSample struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
 type byteReader interface {
 	ReadByte() (byte, error)
 }
@@ -0,0 +1,194 @@
+package proto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// WritePacket writes a single packet to the given writer.
+func WritePacket(writer io.Writer, addr net.Addr, packet *Packet) error {
+	data, ok := packet.Data.([]byte)
+	if !ok {
+		return errors.New("data is not expected byte slice")
+	}
+
+	payload := new(bytes.Buffer)
+	if err := WriteVarInt(payload, packet.PacketID); err != nil {
+		return err
+	}
+	payload.Write(data)
+	packet.Length = payload.Len()
+
+	frame := new(bytes.Buffer)
+	if err := WriteVarInt(frame, packet.Length); err != nil {
+		return err
+	}
+	frame.Write(payload.Bytes())
+
+	if _, err := writer.Write(frame.Bytes()); err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"client": addr,
+		"packet": packet,
+	}).Trace("wrote packet")
+	return nil
+}
+
+// WriteVarInt writes a VarInt to the given writer.
+func WriteVarInt(writer io.Writer, value int) error {
+	uvalue := uint32(value)
+	buf := make([]byte, 0, 5)
+	for {
+		b := byte(uvalue & 0x7F)
+		uvalue >>= 7
+		if uvalue != 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if uvalue == 0 {
+			break
+		}
+	}
+	_, err := writer.Write(buf)
+	return err
+}
+
+// WriteString writes a string prefixed with its length to the given writer.
+func WriteString(writer io.Writer, value string) error {
+	if err := WriteVarInt(writer, len(value)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(writer, value)
+	return err
+}
+
+// WriteStatusResponse writes a StatusResponse packet with the given status to the given writer.
+func WriteStatusResponse(writer io.Writer, addr net.Addr, status *Status) error {
+	content, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	data := new(bytes.Buffer)
+	if err := WriteString(data, string(content)); err != nil {
+		return err
+	}
+	return WritePacket(writer, addr, &Packet{PacketID: StatusResponseID, Data: data.Bytes()})
+}
+
+// WriteStatusRequest writes a StatusRequest packet to the given writer.
+func WriteStatusRequest(writer io.Writer, addr net.Addr) error {
+	return WritePacket(writer, addr, &Packet{PacketID: StatusRequestID, Data: []byte{}})
+}
+
+// WritePong writes a Pong packet with the payload of the ping to the given writer.
+func WritePong(writer io.Writer, addr net.Addr, payload int64) error {
+	data := new(bytes.Buffer)
+	if err := binary.Write(data, binary.BigEndian, payload); err != nil {
+		return err
+	}
+	return WritePacket(writer, addr, &Packet{PacketID: PongID, Data: data.Bytes()})
+}
+
+// WriteDisconnect writes a Disconnect packet with the given reason to the given writer.
+func WriteDisconnect(writer io.Writer, addr net.Addr, reason Chat) error {
+	content, err := json.Marshal(reason)
+	if err != nil {
+		return err
+	}
+
+	data := new(bytes.Buffer)
+	if err := WriteString(data, string(content)); err != nil {
+		return err
+	}
+	return WritePacket(writer, addr, &Packet{PacketID: DisconnectID, Data: data.Bytes()})
+}
+
+// WriteHandshake writes a Handshake packet to the given writer.
+func WriteHandshake(writer io.Writer, addr net.Addr, handshake *Handshake) error {
+	data := new(bytes.Buffer)
+	if err := WriteVarInt(data, handshake.ProtocolVersion); err != nil {
+		return err
+	}
+	if err := WriteString(data, handshake.ServerAddress); err != nil {
+		return err
+	}
+	if err := binary.Write(data, binary.BigEndian, handshake.ServerPort); err != nil {
+		return err
+	}
+	if err := WriteVarInt(data, handshake.NextState); err != nil {
+		return err
+	}
+	return WritePacket(writer, addr, &Packet{PacketID: HandshakeID, Data: data.Bytes()})
+}
+
+// WriteLoginPluginResponse writes a successful LoginPluginResponse packet with the given data to the given writer.
+func WriteLoginPluginResponse(writer io.Writer, addr net.Addr, messageID int, payload []byte) error {
+	data := new(bytes.Buffer)
+	if err := WriteVarInt(data, messageID); err != nil {
+		return err
+	}
+	data.WriteByte(0x01)
+	data.Write(payload)
+	return WritePacket(writer, addr, &Packet{PacketID: LoginPluginResponseID, Data: data.Bytes()})
+}
+
+// WriteLegacyKick writes the kick packet answering a LegacyServerListPing with the given status to the given writer.
+func WriteLegacyKick(writer io.Writer, addr net.Addr, status *Status) error {
+	fields := []string{
+		"\u00a71",
+		strconv.Itoa(status.Version.Protocol),
+		status.Version.Name,
+		descriptionText(status.Description),
+		strconv.Itoa(status.Players.Online),
+		strconv.Itoa(status.Players.Max),
+	}
+	content, _, err := transform.Bytes(unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewEncoder(), []byte(strings.Join(fields, "\x00")))
+	if err != nil {
+		return err
+	}
+
+	data := new(bytes.Buffer)
+	data.WriteByte(LegacyKickID)
+	if err := binary.Write(data, binary.BigEndian, uint16(len(content)/2)); err != nil {
+		return err
+	}
+	data.Write(content)
+
+	if _, err := writer.Write(data.Bytes()); err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"client": addr,
+		"status": status,
+	}).Trace("wrote legacy kick")
+	return nil
+}
+
+// descriptionText returns the plain text of a status description.
+func descriptionText(description interface{}) string {
+	switch d := description.(type) {
+	case string:
+		return d
+	case Chat:
+		return d.Text
+	case *Chat:
+		return d.Text
+	case map[string]interface{}:
+		if text, ok := d["text"].(string); ok {
+			return text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,147 @@
+package proto
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteHandshake(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Handshake Handshake
+	}{
+		{
+			Name:      "Short address",
+			Handshake: Handshake{ProtocolVersion: 763, ServerAddress: "mc.example.com", ServerPort: 25565, NextState: StateLogin},
+		},
+		{
+			Name:      "Long address",
+			Handshake: Handshake{ProtocolVersion: 763, ServerAddress: strings.Repeat("a", 200), ServerPort: 25566, NextState: StateStatus},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			buffer := new(bytes.Buffer)
+			require.NoError(t, WriteHandshake(buffer, nil, &tt.Handshake))
+			size := buffer.Len()
+
+			packet, err := ReadPacket(buffer, nil, StateHandshaking)
+			require.NoError(t, err)
+			assert.Equal(t, HandshakeID, packet.PacketID)
+			assert.Equal(t, size, packet.Size())
+
+			handshake, err := ReadHandshake(packet.Data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.Handshake, *handshake)
+		})
+	}
+}
+
+func TestWriteVarInt(t *testing.T) {
+	for _, value := range []int{0, 1, 127, 128, 25565, 2097151, 2147483647, -1} {
+		t.Run(strconv.Itoa(value), func(t *testing.T) {
+			buffer := new(bytes.Buffer)
+			require.NoError(t, WriteVarInt(buffer, value))
+
+			result, err := readVarInt(buffer)
+			require.NoError(t, err)
+			assert.Equal(t, int32(value), int32(result))
+			assert.Zero(t, buffer.Len())
+		})
+	}
+}
+
+func TestWriteString(t *testing.T) {
+	for _, value := range []string{"", "mc.example.com", "§aMOTD", strings.Repeat("a", 300)} {
+		t.Run(value, func(t *testing.T) {
+			buffer := new(bytes.Buffer)
+			require.NoError(t, WriteString(buffer, value))
+
+			result, err := readString(buffer)
+			require.NoError(t, err)
+			assert.Equal(t, value, result)
+			assert.Zero(t, buffer.Len())
+		})
+	}
+}
+
+func TestWritePacket(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	require.NoError(t, WritePacket(buffer, nil, &Packet{PacketID: 0x2A, Data: []byte{0x01, 0x02, 0x03}}))
+	assert.Equal(t, []byte{0x04, 0x2A, 0x01, 0x02, 0x03}, buffer.Bytes())
+
+	packet, err := ReadPacket(buffer, nil, StateStatus)
+	require.NoError(t, err)
+	assert.Equal(t, &Packet{Length: 4, PacketID: 0x2A, Data: []byte{0x01, 0x02, 0x03}}, packet)
+}
+
+func TestWriteStatusResponse(t *testing.T) {
+	status := &Status{
+		Version:     StatusVersion{Name: "1.20.1", Protocol: 763},
+		Players:     StatusPlayers{Max: 20, Online: 1, Sample: []StatusPlayerSample{{Name: "Notch", ID: "069a79f4-44e9-4a2c-9af1-2a3e5eb14c7b"}}},
+		Description: map[string]interface{}{"text": "A Minecraft Server"},
+	}
+
+	buffer := new(bytes.Buffer)
+	require.NoError(t, WriteStatusResponse(buffer, nil, status))
+
+	packet, err := ReadPacket(buffer, nil, StateStatus)
+	require.NoError(t, err)
+	assert.Equal(t, StatusResponseID, packet.PacketID)
+
+	result, err := ReadStatusResponse(packet.Data)
+	require.NoError(t, err)
+	assert.Equal(t, status, result)
+}
+
+func TestWritePong(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	require.NoError(t, WritePong(buffer, nil, 1234567890123))
+
+	packet, err := ReadPacket(buffer, nil, StateStatus)
+	require.NoError(t, err)
+	assert.Equal(t, PongID, packet.PacketID)
+
+	payload, err := ReadPing(packet.Data)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1234567890123), payload)
+}
+
+func TestWriteDisconnect(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	require.NoError(t, WriteDisconnect(buffer, nil, Chat{Text: "Server is starting"}))
+
+	packet, err := ReadPacket(buffer, nil, StateLogin)
+	require.NoError(t, err)
+	assert.Equal(t, DisconnectID, packet.PacketID)
+
+	reason, err := readString(bytes.NewBuffer(packet.Data.([]byte)))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"text":"Server is starting"}`, reason)
+}
+
+func TestWriteLegacyKick(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	require.NoError(t, WriteLegacyKick(buffer, nil, &Status{
+		Version:     StatusVersion{Name: "1.6.4", Protocol: 78},
+		Players:     StatusPlayers{Max: 20, Online: 3},
+		Description: Chat{Text: "A Minecraft Server"},
+	}))
+
+	id, err := readByte(buffer)
+	require.NoError(t, err)
+	assert.Equal(t, byte(LegacyKickID), id)
+
+	length, err := readUnsignedShort(buffer)
+	require.NoError(t, err)
+	result, err := readUTF16BEString(buffer, length)
+	require.NoError(t, err)
+	assert.Equal(t, "§1\x0078\x001.6.4\x00A Minecraft Server\x003\x0020", result)
+	assert.Zero(t, buffer.Len())
+}
@@ -0,0 +1,213 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxHeaderV1Length is the maximum length of a v1 header including the CRLF.
+	maxHeaderV1Length = 107
+	// headerV2Length is the length of the fixed part of a v2 header.
+	headerV2Length = 16
+)
+
+var prefixV1 = []byte("PROXY ")
+
+// Header is a PROXY protocol header.
+type Header struct {
+	Version     Version
+	Source      net.Addr
+	Destination net.Addr
+}
+
+// Conn is a connection which reports the addresses of its PROXY protocol header.
+type Conn struct {
+	net.Conn
+
+	reader *bufio.Reader
+	header *Header
+}
+
+// Read reads data from the connection after the PROXY protocol header.
+func (c *Conn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns the source address of the PROXY protocol header if present.
+func (c *Conn) RemoteAddr() net.Addr {
+	if c.header != nil && c.header.Source != nil {
+		return c.header.Source
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the destination address of the PROXY protocol header if present.
+func (c *Conn) LocalAddr() net.Addr {
+	if c.header != nil && c.header.Destination != nil {
+		return c.header.Destination
+	}
+	return c.Conn.LocalAddr()
+}
+
+// Header returns the PROXY protocol header of the connection or nil if none was send.
+func (c *Conn) Header() *Header {
+	return c.header
+}
+
+// Accept reads an optional PROXY protocol header from the connection. Headers are only accepted from peers
+// within the trusted networks, if no trusted networks are given no peer is trusted.
+func Accept(conn net.Conn, trusted []*net.IPNet) (*Conn, error) {
+	reader := bufio.NewReader(conn)
+	header, err := ReadHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+	if header != nil && !isTrusted(conn.RemoteAddr(), trusted) {
+		return nil, errors.Errorf("PROXY protocol header from untrusted peer %s", conn.RemoteAddr())
+	}
+
+	return &Conn{
+		Conn:   conn,
+		reader: reader,
+		header: header,
+	}, nil
+}
+
+func isTrusted(addr net.Addr, trusted []*net.IPNet) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, network := range trusted {
+		if network.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadHeader reads a PROXY protocol header of any version from the reader, returns nil if the data does not
+// start with a header.
+func ReadHeader(reader *bufio.Reader) (*Header, error) {
+	first, err := reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	switch first[0] {
+	case prefixV1[0]:
+		data, err := reader.Peek(len(prefixV1))
+		if err != nil || !bytes.Equal(data, prefixV1) {
+			return nil, nil
+		}
+		return readHeaderV1(reader)
+	case signatureV2[0]:
+		data, err := reader.Peek(len(signatureV2))
+		if err != nil || !bytes.Equal(data, signatureV2) {
+			return nil, nil
+		}
+		return readHeaderV2(reader)
+	}
+	return nil, nil
+}
+
+func readHeaderV1(reader *bufio.Reader) (*Header, error) {
+	var line strings.Builder
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line.WriteByte(b)
+		if b == '\n' {
+			break
+		}
+		if line.Len() >= maxHeaderV1Length {
+			return nil, errors.New("PROXY protocol v1 header is too long")
+		}
+	}
+	if !strings.HasSuffix(line.String(), "\r\n") {
+		return nil, errors.New("PROXY protocol v1 header is not terminated by CRLF")
+	}
+
+	fields := strings.Split(strings.TrimSuffix(line.String(), "\r\n"), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return &Header{Version: V1}, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, errors.Errorf("invalid PROXY protocol v1 header %q", line.String())
+	}
+
+	src, err := parseAddrV1(fields[2], fields[4])
+	if err != nil {
+		return nil, err
+	}
+	dst, err := parseAddrV1(fields[3], fields[5])
+	if err != nil {
+		return nil, err
+	}
+	return &Header{Version: V1, Source: src, Destination: dst}, nil
+}
+
+func parseAddrV1(ip string, port string) (*net.TCPAddr, error) {
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return nil, errors.Errorf("invalid PROXY protocol v1 address %q", ip)
+	}
+	parsedPort, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid PROXY protocol v1 port %q", port)
+	}
+	return &net.TCPAddr{IP: parsedIP, Port: int(parsedPort)}, nil
+}
+
+func readHeaderV2(reader *bufio.Reader) (*Header, error) {
+	fixed := make([]byte, headerV2Length)
+	if _, err := io.ReadFull(reader, fixed); err != nil {
+		return nil, err
+	}
+	if fixed[12]&0xF0 != versionV2 {
+		return nil, errors.Errorf("invalid PROXY protocol v2 version %#x", fixed[12]>>4)
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint16(fixed[14:16]))
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, err
+	}
+
+	header := &Header{Version: V2}
+	if fixed[12]&0x0F == commandLocal {
+		return header, nil
+	}
+
+	var ipLength int
+	switch fixed[13] >> 4 {
+	case familyTCP4 >> 4:
+		ipLength = net.IPv4len
+	case familyTCP6 >> 4:
+		ipLength = net.IPv6len
+	default:
+		return header, nil
+	}
+	if len(payload) < 2*ipLength+4 {
+		return nil, errors.New("PROXY protocol v2 header is too short")
+	}
+
+	header.Source = &net.TCPAddr{
+		IP:   net.IP(payload[:ipLength]),
+		Port: int(binary.BigEndian.Uint16(payload[2*ipLength:])),
+	}
+	header.Destination = &net.TCPAddr{
+		IP:   net.IP(payload[ipLength : 2*ipLength]),
+		Port: int(binary.BigEndian.Uint16(payload[2*ipLength+2:])),
+	}
+	return header, nil
+}
@@ -0,0 +1,114 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadHeader(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Version Version
+		Src     *net.TCPAddr
+		Dst     *net.TCPAddr
+	}{
+		{
+			Name:    "V1 TCP4",
+			Version: V1,
+			Src:     &net.TCPAddr{IP: net.ParseIP("203.0.113.7").To4(), Port: 51234},
+			Dst:     &net.TCPAddr{IP: net.ParseIP("10.0.0.1").To4(), Port: 25565},
+		},
+		{
+			Name:    "V2 TCP4",
+			Version: V2,
+			Src:     &net.TCPAddr{IP: net.ParseIP("203.0.113.7").To4(), Port: 51234},
+			Dst:     &net.TCPAddr{IP: net.ParseIP("10.0.0.1").To4(), Port: 25565},
+		},
+		{
+			Name:    "V2 TCP6",
+			Version: V2,
+			Src:     &net.TCPAddr{IP: net.ParseIP("2001:db8::7"), Port: 51234},
+			Dst:     &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 25565},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			buffer := new(bytes.Buffer)
+			require.NoError(t, WriteHeader(buffer, tt.Version, tt.Src, tt.Dst))
+			buffer.Write([]byte{0x10, 0x00})
+
+			reader := bufio.NewReader(buffer)
+			header, err := ReadHeader(reader)
+			require.NoError(t, err)
+			require.NotNil(t, header)
+
+			assert.Equal(t, tt.Version, header.Version)
+			assert.Equal(t, tt.Src.String(), header.Source.String())
+			assert.Equal(t, tt.Dst.String(), header.Destination.String())
+
+			remainder, err := reader.Peek(2)
+			require.NoError(t, err)
+			assert.Equal(t, []byte{0x10, 0x00}, remainder)
+		})
+	}
+}
+
+func TestReadHeaderAbsent(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Input []byte
+	}{
+		{
+			Name:  "Handshake",
+			Input: []byte{0x10, 0x00, 0xF2, 0x05},
+		},
+		{
+			Name:  "Handshake with length of P",
+			Input: append([]byte{0x50, 0x00}, make([]byte, 0x4F)...),
+		},
+		{
+			Name:  "Legacy server list ping",
+			Input: []byte{0xFE, 0x01},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			header, err := ReadHeader(bufio.NewReader(bytes.NewBuffer(tt.Input)))
+			require.NoError(t, err)
+
+			assert.Nil(t, header)
+		})
+	}
+}
+
+func TestIsTrusted(t *testing.T) {
+	_, network, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+	trusted := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 51234}
+	untrusted := &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 51234}
+
+	assert.True(t, isTrusted(trusted, []*net.IPNet{network}))
+	assert.False(t, isTrusted(untrusted, []*net.IPNet{network}))
+	assert.False(t, isTrusted(trusted, nil), "no peer is trusted without trusted networks")
+	assert.False(t, isTrusted(trusted, []*net.IPNet{}), "no peer is trusted without trusted networks")
+}
+
+func TestAcceptWithoutTrustedNetworks(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		WriteHeader(client, V1, &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 51234}, &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 25565})
+	}()
+
+	_, err := Accept(server, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package proxyproto
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Version is the version of the PROXY protocol.
+type Version int
+
+const (
+	// None disables the PROXY protocol.
+	None Version = iota
+	// V1 is the human-readable version of the PROXY protocol.
+	V1
+	// V2 is the binary version of the PROXY protocol.
+	V2
+)
+
+const (
+	versionV2    = 0x20
+	commandLocal = 0x00
+	commandProxy = 0x01
+	familyUnspec = 0x00
+	familyTCP4   = 0x11
+	familyTCP6   = 0x21
+)
+
+var signatureV2 = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+// ParseVersion parses a PROXY protocol version like v1 or v2.
+func ParseVersion(version string) (Version, error) {
+	switch strings.ToLower(version) {
+	case "", "none":
+		return None, nil
+	case "v1", "1":
+		return V1, nil
+	case "v2", "2":
+		return V2, nil
+	}
+	return None, fmt.Errorf("unsupported PROXY protocol version %q", version)
+}
+
+func (v Version) String() string {
+	switch v {
+	case V1:
+		return "v1"
+	case V2:
+		return "v2"
+	}
+	return "none"
+}
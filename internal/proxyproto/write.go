@@ -0,0 +1,81 @@
+package proxyproto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+// WriteHeader writes a PROXY protocol header of the given version for the source and destination to the given writer.
+func WriteHeader(writer io.Writer, version Version, src net.Addr, dst net.Addr) error {
+	var header []byte
+	switch version {
+	case V1:
+		header = headerV1(src, dst)
+	case V2:
+		header = headerV2(src, dst)
+	default:
+		return fmt.Errorf("unsupported PROXY protocol version %d", version)
+	}
+
+	_, err := writer.Write(header)
+	return err
+}
+
+func headerV1(src net.Addr, dst net.Addr) []byte {
+	srcAddr, dstAddr, ok := tcpAddrs(src, dst)
+	if !ok {
+		return []byte("PROXY UNKNOWN\r\n")
+	}
+
+	protocol := "TCP6"
+	if srcAddr.IP.To4() != nil {
+		protocol = "TCP4"
+	}
+	return []byte(fmt.Sprintf("PROXY %s %s %s %d %d\r\n", protocol, srcAddr.IP, dstAddr.IP, srcAddr.Port, dstAddr.Port))
+}
+
+func headerV2(src net.Addr, dst net.Addr) []byte {
+	header := new(bytes.Buffer)
+	header.Write(signatureV2)
+	header.WriteByte(versionV2 | commandProxy)
+
+	srcAddr, dstAddr, ok := tcpAddrs(src, dst)
+	if !ok {
+		header.WriteByte(familyUnspec)
+		binary.Write(header, binary.BigEndian, uint16(0))
+		return header.Bytes()
+	}
+
+	srcIP, dstIP := srcAddr.IP.To4(), dstAddr.IP.To4()
+	if srcIP != nil {
+		header.WriteByte(familyTCP4)
+	} else {
+		srcIP, dstIP = srcAddr.IP.To16(), dstAddr.IP.To16()
+		header.WriteByte(familyTCP6)
+	}
+	binary.Write(header, binary.BigEndian, uint16(len(srcIP)+len(dstIP)+4))
+	header.Write(srcIP)
+	header.Write(dstIP)
+	binary.Write(header, binary.BigEndian, uint16(srcAddr.Port))
+	binary.Write(header, binary.BigEndian, uint16(dstAddr.Port))
+	return header.Bytes()
+}
+
+// tcpAddrs returns the source and destination as tcp addresses of the same family.
+func tcpAddrs(src net.Addr, dst net.Addr) (*net.TCPAddr, *net.TCPAddr, bool) {
+	srcAddr, ok := src.(*net.TCPAddr)
+	if !ok {
+		return nil, nil, false
+	}
+	dstAddr, ok := dst.(*net.TCPAddr)
+	if !ok {
+		return nil, nil, false
+	}
+	if (srcAddr.IP.To4() == nil) != (dstAddr.IP.To4() == nil) {
+		return nil, nil, false
+	}
+	return srcAddr, dstAddr, true
+}
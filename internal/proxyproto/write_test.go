@@ -0,0 +1,56 @@
+package proxyproto
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteHeader(t *testing.T) {
+	src := &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 51234}
+	dst := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 25565}
+
+	tests := []struct {
+		Name     string
+		Version  Version
+		Src      net.Addr
+		Expected []byte
+	}{
+		{
+			Name:     "V1 TCP4",
+			Version:  V1,
+			Src:      src,
+			Expected: []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 25565\r\n"),
+		},
+		{
+			Name:     "V1 mixed families",
+			Version:  V1,
+			Src:      &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 51234},
+			Expected: []byte("PROXY UNKNOWN\r\n"),
+		},
+		{
+			Name:    "V2 TCP4",
+			Version: V2,
+			Src:     src,
+			Expected: append(append([]byte{}, signatureV2...),
+				0x21, 0x11, 0x00, 0x0C,
+				203, 0, 113, 7,
+				10, 0, 0, 1,
+				0xC8, 0x22,
+				0x63, 0xDD,
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			buffer := new(bytes.Buffer)
+			require.NoError(t, WriteHeader(buffer, tt.Version, tt.Src, dst))
+
+			assert.Equal(t, tt.Expected, buffer.Bytes())
+		})
+	}
+}
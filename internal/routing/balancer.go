@@ -0,0 +1,135 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/qumine/ingress-controller/internal/metrics"
+)
+
+// Strategy is the strategy used to select a backend of a route.
+type Strategy string
+
+const (
+	// StrategyRoundRobin selects the backends in turn.
+	StrategyRoundRobin Strategy = "round-robin"
+	// StrategyLeastConnections selects the backend with the least active connections.
+	StrategyLeastConnections Strategy = "least-connections"
+	// StrategyRandom selects a random backend.
+	StrategyRandom Strategy = "random"
+)
+
+var (
+	roundRobinMutex   sync.Mutex
+	roundRobinCounter = make(map[string]int)
+
+	connectionsMutex sync.Mutex
+	// activeConnections are the active connections by their backend.
+	activeConnections = make(map[string]int)
+)
+
+// ParseStrategy parses a strategy like round-robin, least-connections or random.
+func ParseStrategy(strategy string) (Strategy, error) {
+	switch Strategy(strings.ToLower(strategy)) {
+	case "", StrategyRoundRobin:
+		return StrategyRoundRobin, nil
+	case StrategyLeastConnections:
+		return StrategyLeastConnections, nil
+	case StrategyRandom:
+		return StrategyRandom, nil
+	}
+	return "", fmt.Errorf("unsupported strategy %q", strategy)
+}
+
+// SelectBackend selects one of the backends of the route using its strategy or throws an error.
+func (r Route) SelectBackend() (string, error) {
+	if len(r.Backends) == 0 {
+		return "", errors.New("route has no backends")
+	}
+
+	switch r.Strategy {
+	case StrategyLeastConnections:
+		return selectLeastConnections(r.Backends), nil
+	case StrategyRandom:
+		return r.Backends[rand.Intn(len(r.Backends))], nil
+	}
+	return selectRoundRobin(r.Frontend, r.Backends), nil
+}
+
+func selectRoundRobin(frontend string, backends []string) string {
+	roundRobinMutex.Lock()
+	defer roundRobinMutex.Unlock()
+
+	i := roundRobinCounter[frontend] % len(backends)
+	roundRobinCounter[frontend] = i + 1
+	return backends[i]
+}
+
+func selectLeastConnections(backends []string) string {
+	connectionsMutex.Lock()
+	defer connectionsMutex.Unlock()
+
+	selected := backends[0]
+	least := connections(selected)
+	for _, backend := range backends[1:] {
+		if c := connections(backend); c < least {
+			selected = backend
+			least = c
+		}
+	}
+	return selected
+}
+
+// connections returns the amount of active connections to the backend, the caller must hold the connections mutex.
+func connections(backend string) int {
+	return activeConnections[backend]
+}
+
+// Connect counts a new active connection to the backend.
+func Connect(backend string) {
+	connectionsMutex.Lock()
+	defer connectionsMutex.Unlock()
+
+	activeConnections[backend]++
+	metrics.Connections.With(prometheus.Labels{"route": backend}).Set(float64(activeConnections[backend]))
+}
+
+// Disconnect counts a closed connection to the backend. The metrics of the backend are deleted once its last
+// connection is closed if it was removed from the routing in the meantime.
+func Disconnect(backend string) {
+	connectionsMutex.Lock()
+	defer connectionsMutex.Unlock()
+
+	activeConnections[backend]--
+	if activeConnections[backend] > 0 {
+		metrics.Connections.With(prometheus.Labels{"route": backend}).Set(float64(activeConnections[backend]))
+		return
+	}
+	delete(activeConnections, backend)
+	if defaultRouter.hasBackend(backend) {
+		metrics.Connections.With(prometheus.Labels{"route": backend}).Set(0)
+		return
+	}
+	deleteBackendMetrics(backend)
+}
+
+// pruneBackends deletes the metrics of the backends without active connections which are no longer routed by the router.
+func pruneBackends(router *Router, backends []string) {
+	connectionsMutex.Lock()
+	defer connectionsMutex.Unlock()
+
+	for _, backend := range backends {
+		if activeConnections[backend] == 0 && !router.hasBackend(backend) {
+			deleteBackendMetrics(backend)
+		}
+	}
+}
+
+func deleteBackendMetrics(backend string) {
+	metrics.Connections.DeletePartialMatch(prometheus.Labels{"route": backend})
+	metrics.BytesTotal.DeletePartialMatch(prometheus.Labels{"route": backend})
+}
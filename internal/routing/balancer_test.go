@@ -0,0 +1,88 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/qumine/ingress-controller/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectBackendRoundRobin(t *testing.T) {
+	route := NewRoute("lobby.example.com", "10.0.0.1:25565", "10.0.0.2:25565")
+
+	selected := make([]string, 0)
+	for i := 0; i < 4; i++ {
+		backend, err := route.SelectBackend()
+		require.NoError(t, err)
+		selected = append(selected, backend)
+	}
+
+	assert.Equal(t, []string{"10.0.0.1:25565", "10.0.0.2:25565", "10.0.0.1:25565", "10.0.0.2:25565"}, selected)
+}
+
+func TestSelectBackendLeastConnections(t *testing.T) {
+	route := NewRoute("lobby.example.com", "10.0.1.1:25565", "10.0.1.2:25565")
+	route.Strategy = StrategyLeastConnections
+	for i := 0; i < 3; i++ {
+		Connect("10.0.1.1:25565")
+	}
+	Connect("10.0.1.2:25565")
+
+	backend, err := route.SelectBackend()
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.1.2:25565", backend)
+
+	Disconnect("10.0.1.1:25565")
+	Disconnect("10.0.1.1:25565")
+	Disconnect("10.0.1.1:25565")
+	backend, err = route.SelectBackend()
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.1.1:25565", backend)
+	Disconnect("10.0.1.2:25565")
+}
+
+func TestBackendMetrics(t *testing.T) {
+	Add("metrics", NewRoute("metrics.example.com", "10.0.2.1:25565", "10.0.2.2:25565"))
+	Connect("10.0.2.1:25565")
+	Connect("10.0.2.2:25565")
+	Disconnect("10.0.2.2:25565")
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Connections.With(prometheus.Labels{"route": "10.0.2.1:25565"})))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.Connections.With(prometheus.Labels{"route": "10.0.2.2:25565"})))
+
+	// the metrics of removed backends are deleted once they have no connections left
+	Remove("metrics")
+	assert.False(t, metrics.Connections.DeleteLabelValues("10.0.2.2:25565"))
+	Disconnect("10.0.2.1:25565")
+	assert.False(t, metrics.Connections.DeleteLabelValues("10.0.2.1:25565"))
+}
+
+func TestSelectBackendNoBackends(t *testing.T) {
+	_, err := NewRoute("lobby.example.com").SelectBackend()
+	assert.Error(t, err)
+}
+
+func TestParseStrategy(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected Strategy
+	}{
+		{Input: "", Expected: StrategyRoundRobin},
+		{Input: "least-connections", Expected: StrategyLeastConnections},
+		{Input: "Random", Expected: StrategyRandom},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Input, func(t *testing.T) {
+			result, err := ParseStrategy(tt.Input)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.Expected, result)
+		})
+	}
+
+	_, err := ParseStrategy("sticky")
+	assert.Error(t, err)
+}
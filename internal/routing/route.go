@@ -1,15 +1,165 @@
 package routing
 
-// Route represents the route between a frontend and a backend.
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qumine/ingress-controller/internal/forwarding"
+	"github.com/qumine/ingress-controller/internal/proxyproto"
+)
+
+// Route represents the route between a frontend and its backends.
 type Route struct {
 	Frontend string
-	Backend  string
+	Backends []string
+	// Strategy selects one of the backends for a connection.
+	Strategy Strategy
+	// Default marks the route as the fallback for frontends without a matching route.
+	Default bool
+	// OfflineMOTD is the MOTD shown in the server list while the backend is unreachable.
+	OfflineMOTD string
+	// OfflineMessage is the disconnect message for logins while the backend is unreachable.
+	OfflineMessage string
+	// Workload is the workload backing the route, if it should be scaled up on demand.
+	Workload *Workload
+	// IdleTimeout is the duration without connections after which the workload is scaled down, zero disables it.
+	IdleTimeout time.Duration
+	// ProxyProtocol is the version of the PROXY protocol header send to the backend.
+	ProxyProtocol proxyproto.Version
+	// StatusCacheTTL is the duration the status of the backend is cached for status requests, zero relays them.
+	StatusCacheTTL time.Duration
+	// StatusAggregate merges the status of all backends for status requests.
+	StatusAggregate bool
+	// StatusMOTD replaces the MOTD of the status fetched from the backends if not empty.
+	StatusMOTD string
+	// StatusFavicon replaces the favicon of the status fetched from the backends if not empty.
+	StatusFavicon string
+	// Rewrite is the address the handshake of the client is rewritten to, nil relays the handshake as is.
+	Rewrite *Rewrite
+	// Forwarding is the mode used to forward the identity of players to the backend.
+	Forwarding forwarding.Mode
+	// ForwardingSecret is the secret shared with the backend for velocity forwarding.
+	ForwardingSecret *Secret
+
+	// Source is the object the route was created from.
+	Source Source
+	// Pool is the name of the pool of routes sharing their frontend, routes without a pool conflict with each other.
+	Pool string
+	// Priority resolves conflicts between routes with the same frontend, the highest priority wins.
+	Priority int
+	// Created is the creation time of the source, on equal priority the oldest route wins.
+	Created time.Time
+	// Listeners are the names of the restricted listeners serving the route.
+	Listeners []string
+}
+
+// Source references the object a route was created from.
+type Source struct {
+	Namespace string
+	Name      string
+	UID       string
+}
+
+func (s Source) String() string {
+	return s.Namespace + "/" + s.Name
+}
+
+// Workload references the deployment or statefulset backing a route.
+type Workload struct {
+	Namespace string
+	Kind      string
+	Name      string
+}
+
+func (w *Workload) String() string {
+	return w.Namespace + "/" + w.Kind + "/" + w.Name
+}
+
+// Secret references a key of a kubernetes secret.
+type Secret struct {
+	Namespace string
+	Name      string
+	Key       string
+}
+
+func (s *Secret) String() string {
+	return s.Namespace + "/" + s.Name + "/" + s.Key
+}
+
+// Rewrite is the address and port the handshake is rewritten to.
+type Rewrite struct {
+	// Host replaces the address of the handshake if not empty.
+	Host string
+	// Port replaces the port of the handshake if not zero.
+	Port uint16
+	// Backend replaces both with the address of the selected backend.
+	Backend bool
+}
+
+func (r *Rewrite) String() string {
+	if r.Backend {
+		return "backend"
+	}
+	if r.Port == 0 {
+		return r.Host
+	}
+	return net.JoinHostPort(r.Host, strconv.Itoa(int(r.Port)))
+}
+
+// ParseRewrite parses a rewrite like backend, host, host:port or :port.
+func ParseRewrite(rewrite string) (*Rewrite, error) {
+	if strings.ToLower(rewrite) == "backend" {
+		return &Rewrite{Backend: true}, nil
+	}
+	if !strings.Contains(rewrite, ":") {
+		if rewrite == "" {
+			return nil, errors.New("rewrite is empty")
+		}
+		return &Rewrite{Host: rewrite}, nil
+	}
+
+	host, port, err := net.SplitHostPort(rewrite)
+	if err != nil {
+		return nil, err
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || p == 0 {
+		return nil, fmt.Errorf("invalid port %q", port)
+	}
+	return &Rewrite{Host: host, Port: uint16(p)}, nil
+}
+
+// ServesStatus checks if the ingress answers status requests for the route itself instead of relaying them.
+func (r Route) ServesStatus() bool {
+	return r.StatusCacheTTL > 0 || r.StatusAggregate || r.StatusMOTD != "" || r.StatusFavicon != ""
 }
 
 // NewRoute creates a new route.
-func NewRoute(frontend string, backend string) Route {
+func NewRoute(frontend string, backends ...string) Route {
 	return Route{
-		Frontend: frontend,
-		Backend:  backend,
+		Frontend: strings.ToLower(frontend),
+		Backends: backends,
+		Strategy: StrategyRoundRobin,
+	}
+}
+
+// NewDefaultRoute creates a new route which is used as fallback for unknown frontends.
+func NewDefaultRoute(frontend string, backends ...string) Route {
+	route := NewRoute(frontend, backends...)
+	route.Default = true
+	return route
+}
+
+// HasListener checks if the route is served by the restricted listener.
+func (r Route) HasListener(listener string) bool {
+	for _, l := range r.Listeners {
+		if l == listener {
+			return true
+		}
 	}
+	return false
 }
@@ -0,0 +1,55 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRewrite(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Input    string
+		Expected *Rewrite
+	}{
+		{
+			Name:     "Backend",
+			Input:    "backend",
+			Expected: &Rewrite{Backend: true},
+		},
+		{
+			Name:     "Host",
+			Input:    "survival.internal",
+			Expected: &Rewrite{Host: "survival.internal"},
+		},
+		{
+			Name:     "Host and port",
+			Input:    "survival.internal:25566",
+			Expected: &Rewrite{Host: "survival.internal", Port: 25566},
+		},
+		{
+			Name:     "Port",
+			Input:    ":25566",
+			Expected: &Rewrite{Port: 25566},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			result, err := ParseRewrite(tt.Input)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.Expected, result)
+		})
+	}
+}
+
+func TestParseRewriteInvalid(t *testing.T) {
+	for _, rewrite := range []string{"", "survival.internal:", "survival.internal:70000"} {
+		t.Run(rewrite, func(t *testing.T) {
+			_, err := ParseRewrite(rewrite)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -2,51 +2,417 @@ package routing
 
 import (
 	"errors"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/qumine/ingress-controller/internal/metrics"
 	"github.com/sirupsen/logrus"
 )
 
-var routes = make(map[string]Route)
+const (
+	// wildcardPrefix is the prefix of a frontend matching any single-label subdomain.
+	wildcardPrefix = "*."
+)
+
+var defaultRouter = NewRouter()
+
+// Conflict is a route losing against another route with the same frontend.
+type Conflict struct {
+	Frontend string
+	Loser    Route
+	Winner   Route
+}
+
+// Match describes which lookup resolved a frontend.
+type Match string
+
+const (
+	// MatchNone is used if no route matches the frontend.
+	MatchNone Match = "none"
+	// MatchExact is used if a route has exactly the frontend.
+	MatchExact Match = "exact"
+	// MatchWildcard is used if a wildcard route matches the frontend.
+	MatchWildcard Match = "wildcard"
+	// MatchDefault is used if the default route is used for the frontend.
+	MatchDefault Match = "default"
+)
+
+// Lookup is a single lookup performed while resolving a frontend.
+type Lookup struct {
+	Match Match
+	Key   string
+	Found bool
+}
+
+// Resolution is the result of resolving a frontend.
+type Resolution struct {
+	Listener string
+	Frontend string
+	Match    Match
+	Route    Route
+	Lookups  []Lookup
+}
+
+// ConflictHandler is notified about new conflicts.
+type ConflictHandler func(conflict Conflict)
+
+// Router routes frontends to their backends, it is safe for concurrent use.
+type Router struct {
+	mutex sync.RWMutex
+	// routes are the routes by their uid.
+	routes map[string]Route
+	// index are the merged routes by their listener and frontend, the empty listener contains the unrestricted routes.
+	index map[string]map[string]Route
+	// fallback are the merged default routes by their listener.
+	fallback map[string]Route
+	// conflicts are the currently losing routes by their listener, scope and uid.
+	conflicts  map[string]struct{}
+	onConflict ConflictHandler
+}
+
+// NewRouter creates a new router.
+func NewRouter() *Router {
+	return &Router{
+		routes:    make(map[string]Route),
+		index:     make(map[string]map[string]Route),
+		fallback:  make(map[string]Route),
+		conflicts: make(map[string]struct{}),
+	}
+}
 
 // Add a new route to the router.
 func Add(uid string, route Route) {
-	if _, ok := routes[uid]; !ok {
-		routes[uid] = route
-		logrus.WithField("uid", uid).WithField("frontend", route.Frontend).WithField("backend", route.Backend).Info("route created")
-		metrics.Routes.Inc()
-	} else {
+	defaultRouter.Add(uid, route)
+}
+
+// Update an existing route from the router, the route is added if it does not exist.
+func Update(uid string, route Route) {
+	defaultRouter.Update(uid, route)
+}
+
+// Remove an existing route from the router.
+func Remove(uid string) {
+	defaultRouter.Remove(uid)
+}
+
+// OnConflict sets the handler notified about new conflicts of the router.
+func OnConflict(handler ConflictHandler) {
+	defaultRouter.OnConflict(handler)
+}
+
+// Routes returns all routes of the router.
+func Routes() []Route {
+	return defaultRouter.Routes()
+}
+
+// FindBackend finds a route by its frontend and returns a backend selected by its strategy or throws an error.
+func FindBackend(frontend string) (string, error) {
+	return defaultRouter.FindBackend(frontend)
+}
+
+// FindRoute finds a route by its frontend or throws an error.
+func FindRoute(frontend string) (Route, error) {
+	return defaultRouter.FindRoute(frontend)
+}
+
+// FindListenerRoute finds a route served by the listener by its frontend or throws an error.
+func FindListenerRoute(listener string, frontend string) (Route, error) {
+	return defaultRouter.FindListenerRoute(listener, frontend)
+}
+
+// Resolve resolves the frontend for the listener and records every lookup on the way.
+func Resolve(listener string, frontend string) Resolution {
+	return defaultRouter.Resolve(listener, frontend)
+}
+
+// Add a new route to the router.
+func (r *Router) Add(uid string, route Route) {
+	r.mutex.Lock()
+	if _, ok := r.routes[uid]; ok {
+		r.mutex.Unlock()
 		logrus.WithField("uid", uid).Warn("route already created")
+		return
 	}
+	r.routes[uid] = route
+	conflicts := r.reindex(route)
+	r.mutex.Unlock()
+
+	logrus.WithField("uid", uid).WithField("frontend", route.Frontend).WithField("backends", route.Backends).Info("route created")
+	metrics.Routes.Inc()
+	r.notify(conflicts)
 }
 
-// Update an existing route from the router.
-func Update(uid string, route Route) {
-	if _, ok := routes[uid]; ok {
-		routes[uid] = route
-		logrus.WithField("uid", uid).WithField("frontend", route.Frontend).WithField("backend", route.Backend).Info("route updated")
+// Update an existing route from the router, the route is added if it does not exist.
+func (r *Router) Update(uid string, route Route) {
+	r.mutex.Lock()
+	old, ok := r.routes[uid]
+	if !ok {
+		r.mutex.Unlock()
+		r.Add(uid, route)
+		return
 	}
+	r.routes[uid] = route
+	conflicts := append(r.reindex(old), r.reindex(route)...)
+	r.mutex.Unlock()
+
+	logrus.WithField("uid", uid).WithField("frontend", route.Frontend).WithField("backends", route.Backends).Info("route updated")
+	pruneBackends(r, old.Backends)
+	r.notify(conflicts)
 }
 
 // Remove an existing route from the router.
-func Remove(uid string) {
-	if _, ok := routes[uid]; ok {
-		delete(routes, uid)
-		logrus.WithField("uid", uid).Info("route deleted")
-		metrics.Routes.Dec()
+func (r *Router) Remove(uid string) {
+	r.mutex.Lock()
+	old, ok := r.routes[uid]
+	if !ok {
+		r.mutex.Unlock()
+		return
 	}
+	delete(r.routes, uid)
+	conflicts := r.reindex(old)
+	r.mutex.Unlock()
+
+	logrus.WithField("uid", uid).Info("route deleted")
+	metrics.Routes.Dec()
+	pruneBackends(r, old.Backends)
+	r.notify(conflicts)
 }
 
-// FindBackend finds a route by its frontend and returns the backend or throws an error.
-func FindBackend(frontend string) (string, error) {
+// OnConflict sets the handler notified about new conflicts of the router.
+func (r *Router) OnConflict(handler ConflictHandler) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.onConflict = handler
+}
+
+// Routes returns all routes of the router.
+func (r *Router) Routes() []Route {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make([]Route, 0, len(r.routes))
+	for _, route := range r.routes {
+		result = append(result, route)
+	}
+	return result
+}
+
+// hasBackend checks if any route of the router has the backend.
+func (r *Router) hasBackend(backend string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, route := range r.routes {
+		for _, b := range route.Backends {
+			if b == backend {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FindBackend finds a route by its frontend and returns a backend selected by its strategy or throws an error.
+func (r *Router) FindBackend(frontend string) (string, error) {
+	route, err := r.FindRoute(frontend)
+	if err != nil {
+		return "", err
+	}
+	return route.SelectBackend()
+}
+
+// FindRoute finds a route by its frontend or throws an error.
+func (r *Router) FindRoute(frontend string) (Route, error) {
+	return r.FindListenerRoute("", frontend)
+}
+
+// FindListenerRoute finds a route served by the listener by its frontend or throws an error, the empty
+// listener serves all routes.
+// Exact frontends take precedence over wildcard frontends like "*.example.com",
+// which match any single-label subdomain. If neither matches the default route is used.
+// Routes sharing a frontend are merged into a single route with the backends of all of them.
+func (r *Router) FindListenerRoute(listener string, frontend string) (Route, error) {
+	resolution := r.Resolve(listener, frontend)
+	if resolution.Match == MatchNone {
+		return Route{}, errors.New("route not found")
+	}
+	return resolution.Route, nil
+}
+
+// Resolve resolves the frontend for the listener like FindListenerRoute and records every lookup on the way.
+func (r *Router) Resolve(listener string, frontend string) Resolution {
 	frontendParts := strings.Split(frontend, "\x00")
-	frontend = strings.ToLower(frontendParts[0])
+	resolution := Resolution{
+		Listener: listener,
+		Frontend: strings.ToLower(frontendParts[0]),
+		Match:    MatchNone,
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	lookup := func(match Match, key string, route Route, ok bool) bool {
+		resolution.Lookups = append(resolution.Lookups, Lookup{Match: match, Key: key, Found: ok})
+		if ok {
+			resolution.Match = match
+			resolution.Route = route
+		}
+		return ok
+	}
+
+	route, ok := r.index[listener][resolution.Frontend]
+	if lookup(MatchExact, resolution.Frontend, route, ok) {
+		return resolution
+	}
+
+	if wildcard, ok := wildcardFor(resolution.Frontend); ok {
+		route, ok := r.index[listener][wildcard]
+		if lookup(MatchWildcard, wildcard, route, ok) {
+			return resolution
+		}
+	}
+
+	route, ok = r.fallback[listener]
+	lookup(MatchDefault, "", route, ok)
+	return resolution
+}
+
+// reindex rebuilds the merged routes affected by the given route and returns new conflicts,
+// the caller must hold the write lock.
+func (r *Router) reindex(route Route) []Conflict {
+	listeners := route.Listeners
+	if len(listeners) == 0 {
+		listeners = []string{""}
+	}
+	conflicts := make([]Conflict, 0)
+	for _, listener := range listeners {
+		serves := func(other Route) bool {
+			if listener == "" {
+				return len(other.Listeners) == 0
+			}
+			return other.HasListener(listener)
+		}
+
+		conflicts = append(conflicts, r.reindexFrontend(listener, route.Frontend, serves)...)
+		conflicts = append(conflicts, r.reindexDefault(listener, serves)...)
+	}
+
+	metrics.RouteConflicts.Set(float64(len(r.conflicts)))
+	return conflicts
+}
+
+// reindexFrontend rebuilds the merged route of the frontend for the listener, default routes without frontend
+// are only used as fallback.
+func (r *Router) reindexFrontend(listener string, frontend string, serves func(route Route) bool) []Conflict {
+	if frontend == "" {
+		return nil
+	}
+
+	merged, ok, conflicts := r.resolve(listener, "frontend/"+frontend, func(other Route) bool {
+		return serves(other) && other.Frontend == frontend
+	})
+	if ok {
+		if r.index[listener] == nil {
+			r.index[listener] = make(map[string]Route)
+		}
+		r.index[listener][frontend] = merged
+	} else {
+		delete(r.index[listener], frontend)
+	}
+	return conflicts
+}
+
+// reindexDefault rebuilds the merged default route for the listener.
+func (r *Router) reindexDefault(listener string, serves func(route Route) bool) []Conflict {
+	merged, ok, conflicts := r.resolve(listener, "default", func(other Route) bool {
+		return serves(other) && other.Default
+	})
+	if ok {
+		r.fallback[listener] = merged
+	} else {
+		delete(r.fallback, listener)
+	}
+	return conflicts
+}
+
+// resolve merges the winning routes matching the filter and returns the new conflicts within the scope of the listener.
+// Routes are ranked by their priority, creation time and uid, the best route wins together with all routes
+// of its pool.
+func (r *Router) resolve(listener string, scope string, filter func(route Route) bool) (Route, bool, []Conflict) {
+	uids := make([]string, 0)
+	for uid, route := range r.routes {
+		if filter(route) {
+			uids = append(uids, uid)
+		}
+	}
+	sort.Slice(uids, func(i, j int) bool {
+		a, b := r.routes[uids[i]], r.routes[uids[j]]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if !a.Created.Equal(b.Created) {
+			return a.Created.Before(b.Created)
+		}
+		return uids[i] < uids[j]
+	})
 
-	for _, route := range routes {
-		if route.Frontend == frontend {
-			return route.Backend, nil
+	prefix := listener + "|" + scope + "|"
+	previous := make(map[string]struct{})
+	for key := range r.conflicts {
+		if strings.HasPrefix(key, prefix) {
+			previous[key] = struct{}{}
+			delete(r.conflicts, key)
 		}
 	}
-	return "", errors.New("route not found")
+	if len(uids) == 0 {
+		return Route{}, false, nil
+	}
+
+	winner := r.routes[uids[0]]
+	merged := winner
+	merged.Backends = nil
+	conflicts := make([]Conflict, 0)
+	for _, uid := range uids {
+		route := r.routes[uid]
+		if uid == uids[0] || (winner.Pool != "" && route.Pool == winner.Pool) {
+			merged.Backends = append(merged.Backends, route.Backends...)
+			continue
+		}
+
+		key := prefix + uid
+		r.conflicts[key] = struct{}{}
+		if _, ok := previous[key]; !ok {
+			conflicts = append(conflicts, Conflict{Frontend: route.Frontend, Loser: route, Winner: winner})
+		}
+	}
+	return merged, true, conflicts
+}
+
+// notify logs the conflicts and notifies the conflict handler.
+func (r *Router) notify(conflicts []Conflict) {
+	r.mutex.RLock()
+	handler := r.onConflict
+	r.mutex.RUnlock()
+
+	for _, conflict := range conflicts {
+		logrus.WithFields(logrus.Fields{
+			"frontend": conflict.Frontend,
+			"loser":    conflict.Loser.Source,
+			"winner":   conflict.Winner.Source,
+		}).Warn("route conflict")
+		if handler != nil {
+			handler(conflict)
+		}
+	}
+}
+
+// wildcardFor returns the wildcard frontend covering the given frontend.
+func wildcardFor(frontend string) (string, bool) {
+	i := strings.Index(frontend, ".")
+	if i <= 0 || i == len(frontend)-1 {
+		return "", false
+	}
+	return wildcardPrefix + frontend[i+1:], true
 }
@@ -0,0 +1,210 @@
+package routing
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindBackend(t *testing.T) {
+	router := NewRouter()
+	router.Add("exact", NewRoute("lobby.play.example.com", "10.0.0.1:25565"))
+	router.Add("wildcard", NewRoute("*.play.example.com", "10.0.0.2:25565"))
+
+	tests := []struct {
+		Name     string
+		Input    string
+		Expected string
+	}{
+		{
+			Name:     "Exact match",
+			Input:    "lobby.play.example.com",
+			Expected: "10.0.0.1:25565",
+		},
+		{
+			Name:     "Exact match with forge marker",
+			Input:    "LOBBY.play.example.com\x00FML2\x00",
+			Expected: "10.0.0.1:25565",
+		},
+		{
+			Name:     "Wildcard match",
+			Input:    "survival.play.example.com",
+			Expected: "10.0.0.2:25565",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			result, err := router.FindBackend(tt.Input)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.Expected, result)
+		})
+	}
+}
+
+func TestFindBackendNotFound(t *testing.T) {
+	router := NewRouter()
+	router.Add("wildcard", NewRoute("*.play.example.com", "10.0.0.2:25565"))
+
+	for _, frontend := range []string{"play.example.com", "a.b.play.example.com", "example.org"} {
+		t.Run(frontend, func(t *testing.T) {
+			_, err := router.FindBackend(frontend)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFindBackendDefault(t *testing.T) {
+	router := NewRouter()
+	router.Add("exact", NewRoute("lobby.play.example.com", "10.0.0.1:25565"))
+	router.Add("default", NewDefaultRoute("", "10.0.0.3:25565"))
+
+	result, err := router.FindBackend("lobby.play.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1:25565", result)
+
+	result, err = router.FindBackend("203.0.113.7")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.3:25565", result)
+}
+
+func TestFindRouteMergesBackends(t *testing.T) {
+	router := NewRouter()
+	b := NewRoute("lobby.example.com", "10.0.0.2:25565")
+	b.Pool = "lobby"
+	router.Add("b", b)
+	a := NewRoute("lobby.example.com", "10.0.0.1:25565")
+	a.Pool = "lobby"
+	router.Add("a", a)
+
+	route, err := router.FindRoute("lobby.example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"10.0.0.1:25565", "10.0.0.2:25565"}, route.Backends)
+}
+
+func TestRouterConcurrentAccess(t *testing.T) {
+	router := NewRouter()
+	router.Add("default", NewDefaultRoute("", "10.0.0.3:25565"))
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				uid := fmt.Sprintf("%d-%d", i, j)
+				router.Add(uid, NewRoute("lobby.example.com", fmt.Sprintf("10.0.%d.%d:25565", i, j)))
+				router.Update(uid, NewRoute("*.example.com", fmt.Sprintf("10.0.%d.%d:25565", i, j)))
+				router.Remove(uid)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, err := router.FindBackend("lobby.example.com")
+				assert.NoError(t, err)
+				router.Routes()
+			}
+		}()
+	}
+	wg.Wait()
+
+	result, err := router.FindBackend("lobby.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.3:25565", result)
+}
+
+func TestFindRouteConflict(t *testing.T) {
+	router := NewRouter()
+	conflicts := make([]Conflict, 0)
+	router.OnConflict(func(conflict Conflict) {
+		conflicts = append(conflicts, conflict)
+	})
+
+	older := NewRoute("lobby.example.com", "10.0.0.1:25565")
+	older.Created = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := NewRoute("lobby.example.com", "10.0.0.2:25565")
+	newer.Created = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	router.Add("newer", newer)
+	router.Add("older", older)
+
+	result, err := router.FindBackend("lobby.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1:25565", result)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, newer, conflicts[0].Loser)
+
+	newer.Priority = 10
+	router.Update("newer", newer)
+
+	result, err = router.FindBackend("lobby.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2:25565", result)
+	require.Len(t, conflicts, 2)
+	assert.Equal(t, older, conflicts[1].Loser)
+
+	router.Update("newer", newer)
+	assert.Len(t, conflicts, 2)
+}
+
+func TestFindListenerRoute(t *testing.T) {
+	router := NewRouter()
+	conflicts := make([]Conflict, 0)
+	router.OnConflict(func(conflict Conflict) { conflicts = append(conflicts, conflict) })
+	vanilla := NewRoute("play.example.com", "10.0.0.1:25565")
+	vanilla.Created = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	router.Add("vanilla", vanilla)
+	// older routes restricted to a named listener do not take over the hostname on unnamed listeners
+	modded := NewRoute("play.example.com", "10.0.0.2:25565")
+	modded.Listeners = []string{"modded"}
+	modded.Created = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	router.Add("modded", modded)
+	assert.Empty(t, conflicts)
+
+	route, err := router.FindListenerRoute("", "play.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:25565"}, route.Backends)
+
+	route, err = router.FindListenerRoute("modded", "play.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.2:25565"}, route.Backends)
+
+	_, err = router.FindListenerRoute("other", "play.example.com")
+	assert.Error(t, err)
+
+	forge := NewRoute("play.example.com", "10.0.0.3:25565")
+	forge.Listeners = []string{"modded"}
+	forge.Created = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	router.Add("forge", forge)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, forge, conflicts[0].Loser)
+	assert.Equal(t, modded, conflicts[0].Winner)
+}
+
+func TestResolve(t *testing.T) {
+	router := NewRouter()
+	router.Add("wildcard", NewRoute("*.play.example.com", "10.0.0.2:25565"))
+	router.Add("default", NewDefaultRoute("", "10.0.0.3:25565"))
+
+	resolution := router.Resolve("", "Survival.play.example.com\x00FML2\x00")
+	assert.Equal(t, "survival.play.example.com", resolution.Frontend)
+	assert.Equal(t, MatchWildcard, resolution.Match)
+	assert.Equal(t, []string{"10.0.0.2:25565"}, resolution.Route.Backends)
+	assert.Equal(t, []Lookup{
+		{Match: MatchExact, Key: "survival.play.example.com"},
+		{Match: MatchWildcard, Key: "*.play.example.com", Found: true},
+	}, resolution.Lookups)
+
+	resolution = router.Resolve("", "example.org")
+	assert.Equal(t, MatchDefault, resolution.Match)
+	assert.Equal(t, []string{"10.0.0.3:25565"}, resolution.Route.Backends)
+
+	resolution = router.Resolve("public", "example.org")
+	assert.Equal(t, MatchNone, resolution.Match)
+}
@@ -12,12 +12,17 @@ var apiOptions APIOptions
 type APIOptions struct {
 	Host string
 	Port int
+
+	AdminHost string
+	AdminPort int
 }
 
 func GetAPIFlagSet() *pflag.FlagSet {
 	flagSet := &pflag.FlagSet{}
 	flagSet.StringVar(&apiOptions.Host, "api-host", "0.0.0.0", "Host for the API server to listen on")
 	flagSet.IntVar(&apiOptions.Port, "api-port", 8080, "Port for the API server to listen on")
+	flagSet.StringVar(&apiOptions.AdminHost, "admin-host", "127.0.0.1", "Host for the unauthenticated admin API listing and closing connections to listen on")
+	flagSet.IntVar(&apiOptions.AdminPort, "admin-port", 8081, "Port for the admin API to listen on")
 	return flagSet
 }
 
@@ -28,3 +33,7 @@ func GetAPIOptions() APIOptions {
 func (aiOptions *APIOptions) GetAddress() string {
 	return net.JoinHostPort(aiOptions.Host, strconv.Itoa(apiOptions.Port))
 }
+
+func (aiOptions *APIOptions) GetAdminAddress() string {
+	return net.JoinHostPort(aiOptions.AdminHost, strconv.Itoa(aiOptions.AdminPort))
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"net"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 )
@@ -10,14 +12,38 @@ import (
 var ingressOptions IngressOptions
 
 type IngressOptions struct {
-	Host string
-	Port int
+	Host      string
+	Port      int
+	Listeners []string
+
+	DrainTimeout time.Duration
+
+	NotFoundMOTD    string
+	NotFoundVersion string
+	NotFoundMessage string
+	OfflineMOTD     string
+	OfflineMessage  string
+
+	AcceptProxyProtocol       bool
+	ProxyProtocolTrustedCIDRs []string
+
+	AllowUnauthenticatedForwarding bool
 }
 
 func GetIngressFlagSet() *pflag.FlagSet {
 	flagSet := &pflag.FlagSet{}
 	flagSet.StringVar(&ingressOptions.Host, "host", "0.0.0.0", "Host for the API server to listen on")
 	flagSet.IntVar(&ingressOptions.Port, "port", 25565, "Port for the API server to listen on")
+	flagSet.StringSliceVar(&ingressOptions.Listeners, "listeners", []string{}, "Addresses for the ingress to listen on as [name=]host:port, named listeners only serve services with a matching listener annotation, overrides host and port")
+	flagSet.DurationVar(&ingressOptions.DrainTimeout, "drain-timeout", 0, "Time to wait for active connections to close on shutdown before closing them, 0 closes them immediately")
+	flagSet.StringVar(&ingressOptions.NotFoundMOTD, "not-found-motd", "Unknown server", "MOTD shown in the server list for unknown hostnames")
+	flagSet.StringVar(&ingressOptions.NotFoundVersion, "not-found-version", "Unknown", "Version text shown in the server list for unknown hostnames")
+	flagSet.StringVar(&ingressOptions.NotFoundMessage, "not-found-message", "Unknown server", "Disconnect message for logins to unknown hostnames")
+	flagSet.StringVar(&ingressOptions.OfflineMOTD, "offline-motd", "Server is offline", "MOTD shown in the server list while the upstream is unreachable")
+	flagSet.StringVar(&ingressOptions.OfflineMessage, "offline-message", "Server is starting, try again in a minute", "Disconnect message for logins while the upstream is unreachable")
+	flagSet.BoolVar(&ingressOptions.AcceptProxyProtocol, "accept-proxy-protocol", false, "Accept PROXY protocol v1 and v2 headers on the ingress")
+	flagSet.StringSliceVar(&ingressOptions.ProxyProtocolTrustedCIDRs, "proxy-protocol-trusted-cidrs", []string{}, "CIDRs allowed to send PROXY protocol headers, required to accept PROXY protocol headers")
+	flagSet.BoolVar(&ingressOptions.AllowUnauthenticatedForwarding, "allow-unauthenticated-forwarding", false, "Forward the unauthenticated identity of players to services with a forwarding annotation, allows any player to join as any other player")
 	return flagSet
 }
 
@@ -28,3 +54,40 @@ func GetIngressOptions() IngressOptions {
 func (ingressOptions *IngressOptions) GetAddress() string {
 	return net.JoinHostPort(ingressOptions.Host, strconv.Itoa(ingressOptions.Port))
 }
+
+// ListenerOptions are the options of a single ingress listener.
+type ListenerOptions struct {
+	Name    string
+	Address string
+}
+
+func (ingressOptions *IngressOptions) GetListeners() ([]ListenerOptions, error) {
+	if len(ingressOptions.Listeners) == 0 {
+		return []ListenerOptions{{Address: ingressOptions.GetAddress()}}, nil
+	}
+
+	listeners := make([]ListenerOptions, 0, len(ingressOptions.Listeners))
+	for _, entry := range ingressOptions.Listeners {
+		listener := ListenerOptions{Address: entry}
+		if i := strings.Index(entry, "="); i >= 0 {
+			listener.Name, listener.Address = entry[:i], entry[i+1:]
+		}
+		if _, _, err := net.SplitHostPort(listener.Address); err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+func (ingressOptions *IngressOptions) GetProxyProtocolTrustedNetworks() ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(ingressOptions.ProxyProtocolTrustedCIDRs))
+	for _, cidr := range ingressOptions.ProxyProtocolTrustedCIDRs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
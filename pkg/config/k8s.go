@@ -5,12 +5,14 @@ import "github.com/spf13/pflag"
 var k8sOptions K8SOptions
 
 type K8SOptions struct {
-	KubeConfig string
+	KubeConfig      string
+	EndpointRouting bool
 }
 
 func GetK8SFlagSet() *pflag.FlagSet {
 	flagSet := &pflag.FlagSet{}
 	flagSet.StringVar(&k8sOptions.KubeConfig, "kube-config", "", "KubeConfig path")
+	flagSet.BoolVar(&k8sOptions.EndpointRouting, "endpoint-routing", false, "Route directly to the ready endpoints of services instead of their cluster IP")
 	return flagSet
 }
 